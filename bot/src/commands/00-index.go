@@ -4,10 +4,10 @@ import (
 	"database/sql"
 	"go.mills.io/bitcask/v2"
 	"log"
-	"os"
 	"strings"
 	"time"
 	"vaportrader/src/constants"
+	"vaportrader/src/linking"
 	"vaportrader/src/services"
 
 	"github.com/bwmarrin/discordgo"
@@ -24,16 +24,19 @@ var Commands = map[string]Command{}
 
 type CommandContext struct {
 	User    *services.User
+	Locale  string
 	Options map[string]*discordgo.ApplicationCommandInteractionDataOption
 }
 
 type ModalContext struct {
 	User    *services.User
+	Locale  string
 	Options map[string]string
 }
 
 type ActionContext struct {
 	User   *services.User
+	Locale string
 	Action *discordgo.MessageComponentInteractionData
 }
 
@@ -52,28 +55,18 @@ type Command struct {
 	Options      []*discordgo.ApplicationCommandOption
 }
 
-func (c *Command) Register(s *discordgo.Session) {
-
-	s.ApplicationCommandCreate(s.State.User.ID, os.Getenv("TEST_GUILD"), &discordgo.ApplicationCommand{
-		Name:        c.Name,
-		Description: c.Description,
-		Options:     c.Options,
-	})
-}
-
 type CommandHandler struct {
 	icom  map[string]Command
 	index map[string]Command
 	kv    *bitcask.Bitcask
 }
 
-func (c *CommandHandler) Register(s *discordgo.Session, cmd CommandRegisterMethod) {
+func (c *CommandHandler) Register(cmd CommandRegisterMethod) {
 	command := cmd()
 
 	log.Printf("Registering command %v", command.Name)
 
 	c.index[command.Name] = command
-	command.Register(s)
 }
 
 func (c *CommandHandler) HandleCommand(s *discordgo.Session, m *discordgo.InteractionCreate) {
@@ -94,6 +87,65 @@ func (c *CommandHandler) HandleCommand(s *discordgo.Session, m *discordgo.Intera
 	}
 }
 
+// respond sends a localized plain text response to the interaction
+func (c *CommandHandler) respond(s *discordgo.Session, m *discordgo.InteractionCreate, locale string, key string, params *map[string]interface{}) {
+	_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: services.LanguageManager.Get(&locale, key, params),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// respondError sends the localized generic failure message, including the error which caused it
+func (c *CommandHandler) respondError(s *discordgo.Session, m *discordgo.InteractionCreate, locale string, err error) {
+	c.respond(s, m, locale, "commands.handler.errors.generic.failed", &map[string]interface{}{
+		"Error": err.Error(),
+	})
+}
+
+// resolveUser fetches the user who triggered the interaction, creating them if they have never been seen before
+func (c *CommandHandler) resolveUser(m *discordgo.InteractionCreate) (*services.User, error) {
+	var DUser *discordgo.User
+
+	if m.User != nil {
+		DUser = m.User
+	} else {
+		DUser = m.Member.User
+	}
+
+	var id string = "" + DUser.ID
+	var username string = "" + DUser.GlobalName
+
+	user, err := services.DB.GetUserByID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ID == "" {
+		user = &services.User{
+			ID:                id,
+			Name:              username,
+			Entitlements:      uint32(0),
+			Locale:            sql.NullString{Valid: false},
+			WfmID:             sql.NullString{Valid: false},
+			PreferredPlatform: sql.NullString{Valid: false},
+			FirstSeen:         time.Now(),
+			LastSeen:          time.Now(),
+		}
+
+		err = services.DB.Create(user)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
 func (c *CommandHandler) HandleMessageComponent(s *discordgo.Session, m *discordgo.InteractionCreate) {
 	log.Printf("Message component interaction: %v", m.Type)
 
@@ -106,109 +158,48 @@ func (c *CommandHandler) HandleMessageComponent(s *discordgo.Session, m *discord
 		cmdName = "link"
 	case strings.HasPrefix(cmdData.CustomID, "unlink_account_wfm_"):
 		cmdName = "unlink"
+	case strings.HasPrefix(cmdData.CustomID, "lookup_"):
+		cmdName = "lookup"
 	default:
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Unknown command",
-			},
-		})
+		c.respond(s, m, ResolveLocale(m, nil), "commands.handler.errors.unknown", nil)
 		return
 	}
 
 	cmd, ok := c.index[cmdName]
 
 	if !ok {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Unknown command",
-			},
-		})
+		c.respond(s, m, ResolveLocale(m, nil), "commands.handler.errors.unknown", nil)
 		return
 	}
 
-	var DUser *discordgo.User
-
-	if m.User != nil {
-		DUser = m.User
-	} else {
-		DUser = m.Member.User
-	}
-
-	user, err := services.DB.GetUserByID(DUser.ID)
+	user, err := c.resolveUser(m)
 
 	if err != nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error whilst executing command",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		log.Fatalf("Error while fetching user: %v", err)
+		log.Printf("Error while fetching user: %v", err)
+		c.respond(s, m, ResolveLocale(m, nil), "commands.handler.errors.user", nil)
 		return
-	} else if user.ID == "" {
-		user = &services.User{
-			ID:                DUser.ID,
-			Name:              DUser.GlobalName,
-			Entitlements:      uint32(0),
-			Locale:            sql.NullString{Valid: false},
-			WfmID:             sql.NullString{Valid: false},
-			PreferredPlatform: sql.NullString{Valid: false},
-			FirstSeen:         time.Now(),
-			LastSeen:          time.Now(),
-		}
-
-		err = services.DB.Create(user)
-		if err != nil {
-			_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Error whilst executing command",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
-			println(err)
-			return
-		}
 	}
 
 	ctx := ActionContext{
 		User:   user,
+		Locale: ResolveLocale(m, user),
 		Action: &cmdData,
 	}
 
 	if cmd.Action == nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Unknown command",
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unknown", nil)
 		return
 	}
 
 	success, err := cmd.Action(s, m, ctx)
 
 	if err != nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error running command",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		c.respondError(s, m, ctx.Locale, err)
 		return
 	}
 
 	if !success {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Command failed", Flags: discordgo.MessageFlagsEphemeral,
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unsuccessful", nil)
 		return
 	}
 }
@@ -224,69 +215,26 @@ func (c *CommandHandler) HandleModalSubmit(s *discordgo.Session, m *discordgo.In
 		cmdName = "link"
 	case strings.HasPrefix(cmdData.CustomID, "modals_unlink_account_wfm_"):
 		cmdName = "unlink"
+	case strings.HasPrefix(cmdData.CustomID, "modals_ducats_"):
+		cmdName = "ducats"
 	default:
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Unknown command",
-			},
-		})
+		c.respond(s, m, ResolveLocale(m, nil), "commands.handler.errors.unknown", nil)
 		return
 	}
 
 	cmd, ok := c.index[cmdName]
 
-	var DUser *discordgo.User
-
-	if m.User != nil {
-		DUser = m.User
-	} else {
-		DUser = m.Member.User
-	}
-
-	var id string = "" + DUser.ID
-	var username string = "" + DUser.GlobalName
-
-	user, err := services.DB.GetUserByID(id)
+	user, err := c.resolveUser(m)
 
 	if err != nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error whilst executing command",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		log.Fatalf("Error while fetching user: %v", err)
+		log.Printf("Error while fetching user: %v", err)
+		c.respond(s, m, ResolveLocale(m, nil), "commands.handler.errors.user", nil)
 		return
-	} else if user.ID == "" {
-		user = &services.User{
-			ID:                id,
-			Name:              username,
-			Entitlements:      uint32(0),
-			Locale:            sql.NullString{Valid: false},
-			WfmID:             sql.NullString{Valid: false},
-			PreferredPlatform: sql.NullString{Valid: false},
-			FirstSeen:         time.Now(),
-			LastSeen:          time.Now(),
-		}
-
-		err = services.DB.Create(user)
-		if err != nil {
-			_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Error whilst executing command",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
-			println(err)
-			return
-		}
 	}
 
 	ctx := ModalContext{
 		User:    user,
+		Locale:  ResolveLocale(m, user),
 		Options: map[string]string{},
 	}
 
@@ -308,46 +256,25 @@ func (c *CommandHandler) HandleModalSubmit(s *discordgo.Session, m *discordgo.In
 	}
 
 	if !ok {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Unknown command",
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unknown", nil)
 		return
 	}
 
 	if cmd.Modal == nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "No modal handler",
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.no_modal", nil)
 		return
 	}
 
 	success, err := cmd.Modal(s, m, ctx)
 
 	if err != nil {
-		log.Fatal(err.Error())
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error running command",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		log.Printf("Error running modal handler for %s: %v", cmdName, err)
+		c.respondError(s, m, ctx.Locale, err)
 		return
 	}
 
 	if !success {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Command failed", Flags: discordgo.MessageFlagsEphemeral,
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unsuccessful", nil)
 		return
 	}
 }
@@ -363,6 +290,7 @@ func (c *CommandHandler) HandleAutocomplete(s *discordgo.Session, m *discordgo.I
 
 	ctx := CommandContext{
 		User:    nil,
+		Locale:  ResolveLocale(m, nil),
 		Options: map[string]*discordgo.ApplicationCommandInteractionDataOption{},
 	}
 
@@ -371,46 +299,25 @@ func (c *CommandHandler) HandleAutocomplete(s *discordgo.Session, m *discordgo.I
 	}
 
 	if !ok {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Unknown command",
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unknown", nil)
 		return
 	}
 
 	if cmd.Autocomplete == nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "No autocomplete handler",
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.no_autocomplete", nil)
 		return
 	}
 
 	success, err := cmd.Autocomplete(s, m, ctx)
 
 	if err != nil {
-		log.Fatal(err.Error())
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error running command",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		log.Printf("Error running autocomplete handler for %s: %v", cmdData.Name, err)
+		c.respondError(s, m, ctx.Locale, err)
 		return
 	}
 
 	if !success {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Command failed", Flags: discordgo.MessageFlagsEphemeral,
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unsuccessful", nil)
 		return
 	}
 }
@@ -420,57 +327,17 @@ func (c *CommandHandler) HandleApplicationCommand(s *discordgo.Session, m *disco
 
 	cmd, ok := c.index[cmdData.Name]
 
-	var DUser *discordgo.User
-
-	if m.User != nil {
-		DUser = m.User
-	} else {
-		DUser = m.Member.User
-	}
-
-	var id string = "" + DUser.ID
-	var username string = "" + DUser.GlobalName
-
-	user, err := services.DB.GetUserByID(id)
+	user, err := c.resolveUser(m)
 
 	if err != nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error whilst executing command",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
-		log.Fatalf("Error while fetching user: %v", err)
+		log.Printf("Error while fetching user: %v", err)
+		c.respond(s, m, ResolveLocale(m, nil), "commands.handler.errors.user", nil)
 		return
-	} else if user.ID == "" {
-		user = &services.User{
-			ID:                id,
-			Name:              username,
-			Entitlements:      uint32(0),
-			Locale:            sql.NullString{Valid: false},
-			WfmID:             sql.NullString{Valid: false},
-			PreferredPlatform: sql.NullString{Valid: false},
-			FirstSeen:         time.Now(),
-			LastSeen:          time.Now(),
-		}
-
-		err = services.DB.Create(user)
-		if err != nil {
-			_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Error whilst executing command",
-					Flags:   discordgo.MessageFlagsEphemeral,
-				},
-			})
-			println(err)
-			return
-		}
 	}
 
 	ctx := CommandContext{
 		User:    user,
+		Locale:  ResolveLocale(m, user),
 		Options: map[string]*discordgo.ApplicationCommandInteractionDataOption{},
 	}
 
@@ -479,12 +346,7 @@ func (c *CommandHandler) HandleApplicationCommand(s *discordgo.Session, m *disco
 	}
 
 	if !ok {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Unknown command",
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unknown", nil)
 		return
 	}
 
@@ -496,10 +358,10 @@ func (c *CommandHandler) HandleApplicationCommand(s *discordgo.Session, m *disco
 			Data: &discordgo.InteractionResponseData{
 				Embeds: []*discordgo.MessageEmbed{
 					{
-						Title:       "Error Checking Permissions",
-						Description: "An error was encountered while we tried to verify your access to this command.",
+						Title:       ctx.Translate("commands.handler.perms.failed.title", nil),
+						Description: ctx.Translate("commands.handler.perms.failed.description", nil),
 						Color:       constants.ThemeColor,
-						Footer:      constants.Footer,
+						Footer:      Footer(ctx.Locale),
 					},
 				},
 				Flags: discordgo.MessageFlagsEphemeral,
@@ -514,17 +376,17 @@ func (c *CommandHandler) HandleApplicationCommand(s *discordgo.Session, m *disco
 			Data: &discordgo.InteractionResponseData{
 				Embeds: []*discordgo.MessageEmbed{
 					{
-						Title:       "Not Authorized",
-						Description: "You are unable to use this command",
+						Title:       ctx.Translate("commands.handler.perms.unauthorized.title", nil),
+						Description: ctx.Translate("commands.handler.perms.unauthorized.description", nil),
 						Fields: []*discordgo.MessageEmbedField{
 							{
-								Name:   "Reason",
+								Name:   ctx.Translate("commands.handler.perms.unauthorized.reason", nil),
 								Value:  reason,
 								Inline: false,
 							},
 						},
 						Color:  constants.ThemeColor,
-						Footer: constants.Footer,
+						Footer: Footer(ctx.Locale),
 					},
 				},
 				Flags: discordgo.MessageFlagsEphemeral,
@@ -536,23 +398,12 @@ func (c *CommandHandler) HandleApplicationCommand(s *discordgo.Session, m *disco
 	success, err := cmd.Handler(s, m, ctx)
 
 	if err != nil {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Error running command",
-				Flags:   discordgo.MessageFlagsEphemeral,
-			},
-		})
+		c.respondError(s, m, ctx.Locale, err)
 		return
 	}
 
 	if !success {
-		_ = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Command failed", Flags: discordgo.MessageFlagsEphemeral,
-			},
-		})
+		c.respond(s, m, ctx.Locale, "commands.handler.errors.unsuccessful", nil)
 		return
 	}
 
@@ -567,18 +418,29 @@ var CMDHandler = &CommandHandler{
 }
 
 func Load(s *discordgo.Session) {
-	// commands, err := s.ApplicationCommands(s.State.User.ID, os.Getenv("TEST_GUILD"))
-
-	// if err != nil {
-	// 	log.Fatal(err.Error())
-	// 	return
-	// }
+	CMDHandler.Register(InfoCommand)
+	CMDHandler.Register(LinkCommand)
+	CMDHandler.Register(UnlinkCommand)
+	CMDHandler.Register(AccountsCommand)
+	CMDHandler.Register(ProfileCommand)
+	CMDHandler.Register(LookupCommand)
+	CMDHandler.Register(ItemCommand)
+	CMDHandler.Register(FlipsCommand)
+	CMDHandler.Register(DucatsCommand)
+	CMDHandler.Register(SettingsCommand)
+	CMDHandler.Register(AdminCommand)
+
+	// Tell users how their account links end, now that we can reach Discord
+	linking.Sessions.OnTransition(LinkNotifier(s))
+	services.SetAccountRenamedHook(RenameNotifier(s))
+	services.SetAccountBannedHook(BanNotifier(s))
+	services.SetBadgeAwardedHook(BadgeNotifier(s))
+	services.SetAlertTriggeredHook(AlertNotifier(s))
+	services.Sets.SetOpportunityHook(SetFlipNotifier(s))
+
+	_, err := NewCommandRegistrar(ScopeFromEnv()).Sync(s, CMDHandler.index)
 
-	// for _, command := range commands {
-	// 	s.ApplicationCommandDelete(s.State.User.ID, os.Getenv("TEST_GUILD"), command.ID)
-	// }
-
-	CMDHandler.Register(s, InfoCommand)
-	CMDHandler.Register(s, LinkCommand)
-	CMDHandler.Register(s, ItemCommand)
+	if err != nil {
+		log.Fatalf("Error synchronizing commands: %v", err)
+	}
 }
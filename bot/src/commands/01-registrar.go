@@ -0,0 +1,333 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandScope decides whether commands are registered globally, or for a single guild
+type CommandScope struct {
+	// The ID of the guild to register the commands in, or an empty string for global commands
+	GuildID string
+}
+
+// IsGlobal returns true if the commands are registered globally
+func (c CommandScope) IsGlobal() bool {
+	return c.GuildID == ""
+}
+
+func (c CommandScope) String() string {
+	if c.IsGlobal() {
+		return "global"
+	}
+
+	return "guild " + c.GuildID
+}
+
+// ScopeFromEnv reads the command scope from the environment.
+// Commands are registered in TEST_GUILD when it is set, and globally otherwise,
+// unless GLOBAL_COMMANDS is set to "true", in which case they are always global.
+func ScopeFromEnv() CommandScope {
+	if os.Getenv("GLOBAL_COMMANDS") == "true" {
+		return CommandScope{}
+	}
+
+	return CommandScope{GuildID: os.Getenv("TEST_GUILD")}
+}
+
+// CommandDiff describes the changes required to bring the remote commands in line with the local ones
+type CommandDiff struct {
+	Create    []string
+	Update    []string
+	Delete    []string
+	Unchanged []string
+}
+
+// IsEmpty returns true if the remote commands are already up to date
+func (d CommandDiff) IsEmpty() bool {
+	return len(d.Create) == 0 && len(d.Update) == 0 && len(d.Delete) == 0
+}
+
+func (d CommandDiff) String() string {
+	return fmt.Sprintf("%d to create %v, %d to update %v, %d to delete %v, %d unchanged",
+		len(d.Create), d.Create,
+		len(d.Update), d.Update,
+		len(d.Delete), d.Delete,
+		len(d.Unchanged),
+	)
+}
+
+// CommandRegistrar synchronizes the locally defined commands with Discord
+type CommandRegistrar struct {
+	Scope CommandScope
+}
+
+func NewCommandRegistrar(scope CommandScope) *CommandRegistrar {
+	return &CommandRegistrar{
+		Scope: scope,
+	}
+}
+
+// Desired builds the full set of application commands from the command index
+func (r *CommandRegistrar) Desired(index map[string]Command) []*discordgo.ApplicationCommand {
+	names := make([]string, 0, len(index))
+
+	for name := range index {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	desired := make([]*discordgo.ApplicationCommand, 0, len(names))
+
+	for _, name := range names {
+		cmd := index[name]
+		desired = append(desired, cmd.ApplicationCommand())
+	}
+
+	return desired
+}
+
+// Diff compares the desired commands with those currently registered on Discord
+func (r *CommandRegistrar) Diff(desired []*discordgo.ApplicationCommand, remote []*discordgo.ApplicationCommand) CommandDiff {
+	diff := CommandDiff{}
+
+	remoteIndex := map[string]*discordgo.ApplicationCommand{}
+
+	for _, cmd := range remote {
+		remoteIndex[cmd.Name] = cmd
+	}
+
+	for _, cmd := range desired {
+		existing, ok := remoteIndex[cmd.Name]
+
+		if !ok {
+			diff.Create = append(diff.Create, cmd.Name)
+			continue
+		}
+
+		delete(remoteIndex, cmd.Name)
+
+		if commandsEqual(cmd, existing) {
+			diff.Unchanged = append(diff.Unchanged, cmd.Name)
+		} else {
+			diff.Update = append(diff.Update, cmd.Name)
+		}
+	}
+
+	for name := range remoteIndex {
+		diff.Delete = append(diff.Delete, name)
+	}
+
+	sort.Strings(diff.Delete)
+
+	return diff
+}
+
+// Sync fetches the registered commands, and overwrites them in a single request if anything has changed.
+// Commands which are registered on Discord, but no longer exist locally are removed by the overwrite.
+func (r *CommandRegistrar) Sync(s *discordgo.Session, index map[string]Command) (CommandDiff, error) {
+	desired := r.Desired(index)
+
+	remote, err := s.ApplicationCommands(s.State.User.ID, r.Scope.GuildID)
+
+	if err != nil {
+		return CommandDiff{}, err
+	}
+
+	diff := r.Diff(desired, remote)
+
+	if diff.IsEmpty() {
+		log.Printf("Commands (%s) are up to date - %d registered", r.Scope, len(diff.Unchanged))
+		return diff, nil
+	}
+
+	log.Printf("Synchronizing commands (%s): %s", r.Scope, diff)
+
+	_, err = s.ApplicationCommandBulkOverwrite(s.State.User.ID, r.Scope.GuildID, desired)
+
+	if err != nil {
+		return diff, err
+	}
+
+	return diff, nil
+}
+
+// ApplicationCommand builds the Discord representation of the command, including localized names and descriptions
+func (c *Command) ApplicationCommand() *discordgo.ApplicationCommand {
+	prefix := "commands.discord." + c.Name
+
+	cmd := &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        c.Name,
+		Description: c.Description,
+		Options:     localizeOptions(prefix, c.Options),
+	}
+
+	if names := services.LanguageManager.Localizations(prefix + ".name"); len(names) > 0 {
+		cmd.NameLocalizations = &names
+	}
+
+	if descriptions := services.LanguageManager.Localizations(prefix + ".description"); len(descriptions) > 0 {
+		cmd.DescriptionLocalizations = &descriptions
+	}
+
+	return cmd
+}
+
+// localizeOptions fills in the localized descriptions of the given options (and any nested sub-commands)
+// using keys in the form of "<prefix>.options.<option>.description"
+func localizeOptions(prefix string, options []*discordgo.ApplicationCommandOption) []*discordgo.ApplicationCommandOption {
+	if options == nil {
+		return []*discordgo.ApplicationCommandOption{}
+	}
+
+	for _, option := range options {
+		key := prefix + ".options." + option.Name
+
+		if descriptions := services.LanguageManager.Localizations(key + ".description"); len(descriptions) > 0 {
+			option.DescriptionLocalizations = descriptions
+		}
+
+		option.Options = localizeOptions(key, option.Options)
+	}
+
+	return options
+}
+
+func commandsEqual(a *discordgo.ApplicationCommand, b *discordgo.ApplicationCommand) bool {
+	if a.Name != b.Name || a.Description != b.Description {
+		return false
+	}
+
+	if a.Type != b.Type && a.Type != 0 && b.Type != 0 {
+		return false
+	}
+
+	if !localizationsEqual(derefLocalizations(a.NameLocalizations), derefLocalizations(b.NameLocalizations)) {
+		return false
+	}
+
+	if !localizationsEqual(derefLocalizations(a.DescriptionLocalizations), derefLocalizations(b.DescriptionLocalizations)) {
+		return false
+	}
+
+	if !pointersEqual(a.DefaultMemberPermissions, b.DefaultMemberPermissions) {
+		return false
+	}
+
+	return optionsEqual(a.Options, b.Options)
+}
+
+func optionsEqual(a []*discordgo.ApplicationCommandOption, b []*discordgo.ApplicationCommandOption) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		x, y := a[index], b[index]
+
+		if x.Type != y.Type || x.Name != y.Name || x.Description != y.Description {
+			return false
+		}
+
+		if x.Required != y.Required || x.Autocomplete != y.Autocomplete {
+			return false
+		}
+
+		if !localizationsEqual(x.NameLocalizations, y.NameLocalizations) {
+			return false
+		}
+
+		if !localizationsEqual(x.DescriptionLocalizations, y.DescriptionLocalizations) {
+			return false
+		}
+
+		if !pointersEqual(x.MinValue, y.MinValue) || x.MaxValue != y.MaxValue {
+			return false
+		}
+
+		if !pointersEqual(x.MinLength, y.MinLength) || x.MaxLength != y.MaxLength {
+			return false
+		}
+
+		if !channelTypesEqual(x.ChannelTypes, y.ChannelTypes) {
+			return false
+		}
+
+		if len(x.Choices) != len(y.Choices) {
+			return false
+		}
+
+		for choice := range x.Choices {
+			if x.Choices[choice].Name != y.Choices[choice].Name {
+				return false
+			}
+
+			if !localizationsEqual(x.Choices[choice].NameLocalizations, y.Choices[choice].NameLocalizations) {
+				return false
+			}
+
+			// Remote values are decoded from JSON, so compare their string representations
+			if fmt.Sprint(x.Choices[choice].Value) != fmt.Sprint(y.Choices[choice].Value) {
+				return false
+			}
+		}
+
+		if !optionsEqual(x.Options, y.Options) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func localizationsEqual(a map[discordgo.Locale]string, b map[discordgo.Locale]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for locale, value := range a {
+		if b[locale] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func channelTypesEqual(a []discordgo.ChannelType, b []discordgo.ChannelType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pointersEqual compares optional values, which are equal when both are unset or both hold the same value
+func pointersEqual[T comparable](a *T, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func derefLocalizations(l *map[discordgo.Locale]string) map[discordgo.Locale]string {
+	if l == nil {
+		return nil
+	}
+
+	return *l
+}
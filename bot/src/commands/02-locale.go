@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ResolveLocale picks the language used to respond to an interaction.
+// The user's saved locale wins, followed by the locale of their Discord client,
+// then the preferred locale of the guild, and finally the default locale.
+func ResolveLocale(m *discordgo.InteractionCreate, user *services.User) string {
+	var candidates []string
+
+	if user != nil && user.Locale.Valid {
+		candidates = append(candidates, user.Locale.String)
+	}
+
+	candidates = append(candidates, string(m.Locale))
+
+	if m.GuildLocale != nil {
+		candidates = append(candidates, string(*m.GuildLocale))
+	}
+
+	return services.LanguageManager.Resolve(candidates...)
+}
+
+// GuildLocale picks the language used to post in a guild outside of an interaction, such as in a feed.
+// The preferred locale of the guild wins, followed by the default locale.
+func GuildLocale(s *discordgo.Session, guildID string) string {
+	guild, err := s.State.Guild(guildID)
+
+	if err != nil {
+		guild, err = s.Guild(guildID)
+	}
+
+	if err != nil {
+		return services.LanguageManager.Resolve()
+	}
+
+	return services.LanguageManager.Resolve(guild.PreferredLocale)
+}
+
+// Footer builds the localized embed footer
+func Footer(locale string) *discordgo.MessageEmbedFooter {
+	return &discordgo.MessageEmbedFooter{
+		Text: constants.BotName + " " + constants.Version + " | " + services.LanguageManager.Get(&locale, "constants.bot.footer", &map[string]interface{}{
+			"Maintainer": constants.Author,
+		}),
+	}
+}
+
+// Translate returns the value of the given key in the locale of this interaction
+func (c CommandContext) Translate(key string, params *map[string]interface{}) string {
+	return services.LanguageManager.Get(&c.Locale, key, params)
+}
+
+// Translate returns the value of the given key in the locale of this interaction
+func (c ModalContext) Translate(key string, params *map[string]interface{}) string {
+	return services.LanguageManager.Get(&c.Locale, key, params)
+}
+
+// Translate returns the value of the given key in the locale of this interaction
+func (c ActionContext) Translate(key string, params *map[string]interface{}) string {
+	return services.LanguageManager.Get(&c.Locale, key, params)
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"log"
+	"vaportrader/src/constants"
+	"vaportrader/src/linking"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// LinkNotifier tells Discord users how their account link ended, or that their code was sent from the wrong account.
+// The message showing their code is edited while its interaction token is valid, otherwise they are sent a DM instead.
+func LinkNotifier(s *discordgo.Session) linking.Listener {
+	return func(session *services.LinkSession, from linking.State, event linking.Event) {
+		var key string
+
+		switch event {
+		case linking.EventVerify:
+			key = "linked"
+		case linking.EventWrongSender:
+			key = "wrong_account"
+		case linking.EventExpire:
+			// Users who never got past the username prompt have nothing to update
+			if !session.InteractionToken.Valid {
+				return
+			}
+
+			key = "expired"
+		default:
+			return
+		}
+
+		locale := linkLocale(session.UserID)
+		embed := linkResultEmbed(session, key, locale)
+
+		if interaction := linking.Interaction(session); interaction != nil {
+			_, err := s.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+				Embeds: &[]*discordgo.MessageEmbed{embed},
+				// A wrong sender can still retry with the same code, so keep the button to the profile
+				Components: linkResultComponents(event),
+			})
+
+			if err == nil {
+				return
+			}
+
+			log.Printf("Error editing the link message of %s, sending a DM instead: %s", session.UserID, err)
+		}
+
+		err := sendDM(s, session.UserID, embed)
+
+		if err != nil {
+			log.Printf("Error sending the link result to %s: %s", session.UserID, err)
+		}
+	}
+}
+
+// RenameNotifier tells Discord users when the in-game name of one of their linked accounts changes
+func RenameNotifier(s *discordgo.Session) func(account *services.LinkedAccount, previous string) {
+	return func(account *services.LinkedAccount, previous string) {
+		locale := linkLocale(account.UserID)
+		params := &map[string]interface{}{
+			"Previous":    previous,
+			"AccountName": account.Username,
+			"Platform":    platformName(account.Platform),
+		}
+
+		err := sendDM(s, account.UserID, &discordgo.MessageEmbed{
+			Title:       services.LanguageManager.Get(&locale, "commands.discord.accounts.renamed.title", params),
+			Description: services.LanguageManager.Get(&locale, "commands.discord.accounts.renamed.description", params),
+			Color:       constants.ThemeColor,
+			Footer:      Footer(locale),
+		})
+
+		if err != nil {
+			log.Printf("Error telling %s about their renamed account: %s", account.UserID, err)
+		}
+	}
+}
+
+// BanNotifier tells Discord users when warframe.market bans one of their linked accounts
+func BanNotifier(s *discordgo.Session) func(account *services.LinkedAccount) {
+	return func(account *services.LinkedAccount) {
+		locale := linkLocale(account.UserID)
+		params := &map[string]interface{}{
+			"AccountName": account.Username,
+			"Platform":    platformName(account.Platform),
+		}
+
+		err := sendDM(s, account.UserID, &discordgo.MessageEmbed{
+			Title:       services.LanguageManager.Get(&locale, "commands.discord.accounts.banned.title", params),
+			Description: services.LanguageManager.Get(&locale, "commands.discord.accounts.banned.description", params),
+			Color:       constants.ThemeColor,
+			Footer:      Footer(locale),
+		})
+
+		if err != nil {
+			log.Printf("Error telling %s about their banned account: %s", account.UserID, err)
+		}
+	}
+}
+
+// linkResultEmbed describes how the link session ended, with key being one of "linked", "expired" or "wrong_account"
+func linkResultEmbed(session *services.LinkSession, key string, locale string) *discordgo.MessageEmbed {
+	params := &map[string]interface{}{
+		"AccountName": session.ProfileName.String,
+		"CommandName": services.LanguageManager.Get(&locale, "commands.discord.link.name", nil),
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       services.LanguageManager.Get(&locale, "commands.discord.link.result."+key+".title", params),
+		Description: services.LanguageManager.Get(&locale, "commands.discord.link.result."+key+".description", params),
+		Color:       constants.ThemeColor,
+		Footer:      Footer(locale),
+	}
+}
+
+// linkResultComponents returns the buttons left on the link message after the event
+func linkResultComponents(event linking.Event) *[]discordgo.MessageComponent {
+	if event == linking.EventWrongSender {
+		return nil
+	}
+
+	return &[]discordgo.MessageComponent{}
+}
+
+// linkLocale returns the language the Discord user chose, then the locale of their warframe.market profile, then the default locale
+func linkLocale(userID string) string {
+	user, err := services.DB.GetUserByID(userID)
+
+	if err != nil || user == nil {
+		return services.LanguageManager.Resolve()
+	}
+
+	// There is no Discord client locale outside of an interaction, so their warframe.market one is the next best guess
+	return services.LanguageManager.Resolve(user.Locale.String, user.WfmLocale.String)
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BadgeNotifier congratulates Discord users on every badge they are awarded
+func BadgeNotifier(s *discordgo.Session) func(userID string, badge *services.Badge) {
+	return func(userID string, badge *services.Badge) {
+		// Shadow identities have no Discord account to message, their badges are shown once they link
+		if (&services.User{ID: userID}).IsShadow() {
+			return
+		}
+
+		locale := linkLocale(userID)
+		params := &map[string]interface{}{
+			"Badge":       badge.Name,
+			"Icon":        badge.Icon,
+			"Description": badge.Description,
+		}
+
+		err := sendDM(s, userID, &discordgo.MessageEmbed{
+			Title:       services.LanguageManager.Get(&locale, "commands.discord.profile.awarded.title", params),
+			Description: services.LanguageManager.Get(&locale, "commands.discord.profile.awarded.description", params),
+			Color:       constants.ThemeColor,
+			Footer:      Footer(locale),
+		})
+
+		if err != nil {
+			log.Printf("Error telling %s about their badge %d: %s", userID, badge.ID, err)
+		}
+	}
+}
+
+// AlertNotifier tells Discord users about the orders matching their price alerts
+func AlertNotifier(s *discordgo.Session) func(alert *services.Alert, order *services.BookOrder) {
+	return func(alert *services.Alert, order *services.BookOrder) {
+		if (&services.User{ID: alert.UserId}).IsShadow() {
+			return
+		}
+
+		locale := linkLocale(alert.UserId)
+		item := services.Items.Name(order.ItemID, &locale)
+
+		if item == "" {
+			item = order.Slug
+		}
+
+		params := &map[string]interface{}{
+			"Item":     item,
+			"Price":    services.LanguageManager.FormatPlatinum(&locale, int64(order.Price)),
+			"Quantity": order.Quantity,
+			"Seller":   order.UserName,
+			"Platform": platformName(order.Platform),
+		}
+
+		key := order.OrderType
+
+		// In-game alerts are about the seller rather than the price, so they come with a whisper to send right away
+		if alert.PriceMode == services.AlertModeInGame {
+			key = services.AlertModeInGame
+			(*params)["Whisper"] = services.Whisper(order.UserName, order.OrderType, services.Items.Name(order.ItemID, nil), order.Rank, order.Price)
+		}
+
+		err := sendDM(s, alert.UserId, &discordgo.MessageEmbed{
+			Title:       services.LanguageManager.Get(&locale, "commands.discord.alerts.triggered."+key+".title", params),
+			Description: services.LanguageManager.Get(&locale, "commands.discord.alerts.triggered."+key+".description", params),
+			URL:         fmt.Sprintf("https://warframe.market/items/%s", order.Slug),
+			Color:       constants.ThemeColor,
+			Footer:      Footer(locale),
+		})
+
+		if err != nil {
+			log.Printf("Error telling %s about alert %d: %s", alert.UserId, alert.ID, err)
+		}
+	}
+}
+
+// How long a set flip stays quiet in a channel after being posted, so a flickering price doesn't flood it
+const flipNotifyCooldown = time.Hour
+
+// SetFlipNotifier posts set flips in the channels subscribed to them, once they become profitable enough
+func SetFlipNotifier(s *discordgo.Session) func(opportunity *services.SetOpportunity, previous *services.SetOpportunity) {
+	var mu sync.Mutex
+	posted := map[string]time.Time{} // Channel and set -> when the flip was last posted
+
+	return func(opportunity *services.SetOpportunity, previous *services.SetOpportunity) {
+		// Only flips which got better are new, anything else was already judged
+		if opportunity.Margin <= 0 || (previous != nil && previous.Direction == opportunity.Direction && previous.Margin >= opportunity.Margin) {
+			return
+		}
+
+		subscriptions, err := services.DB.GetGuildSubscriptions(services.SubscriptionSetFlips)
+
+		if err != nil {
+			log.Printf("Error reading the set flip subscriptions: %s", err)
+			return
+		}
+
+		for _, subscription := range subscriptions {
+			if opportunity.Margin < int(subscription.MinMargin) {
+				continue
+			}
+
+			// A flip which was already good enough has been posted before
+			if previous != nil && previous.Direction == opportunity.Direction && previous.Margin >= int(subscription.MinMargin) {
+				continue
+			}
+
+			key := subscription.ChannelID + "/" + opportunity.SetID
+
+			mu.Lock()
+			recent := time.Since(posted[key]) < flipNotifyCooldown
+
+			if !recent {
+				posted[key] = time.Now()
+			}
+
+			mu.Unlock()
+
+			if recent {
+				continue
+			}
+
+			locale := GuildLocale(s, subscription.GuildID)
+
+			_, err := s.ChannelMessageSendEmbed(subscription.ChannelID, &discordgo.MessageEmbed{
+				Title:       services.LanguageManager.Get(&locale, "commands.discord.flips.notification.title", nil),
+				Description: services.LanguageManager.Get(&locale, "commands.discord.flips.notification.description", flipParams(locale, opportunity)),
+				Color:       constants.ThemeColor,
+				Footer:      Footer(locale),
+			})
+
+			if err != nil {
+				log.Printf("Error posting set flip %s in %s: %s", opportunity.SetSlug, subscription.ChannelID, err)
+			}
+		}
+	}
+}
+
+// sendDM sends the embed to the Discord user as a direct message
+func sendDM(s *discordgo.Session, userID string, embed *discordgo.MessageEmbed) error {
+	channel, err := s.UserChannelCreate(userID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ChannelMessageSendEmbed(channel.ID, embed)
+
+	return err
+}
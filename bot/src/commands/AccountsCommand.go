@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func AccountsCommand() Command {
+	return Command{
+		Name:        "accounts",
+		Description: "Manage the Warframe Market accounts linked to your Discord account.",
+		Usage:       "accounts default platform: PC",
+		Category:    "Utility",
+		Cooldown:    5 * time.Second,
+		Handler:     AccountsHandler,
+		Permissions: AccountsPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "list",
+				Description: "List your linked accounts.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "default",
+				Description: "Choose the account used when no platform is given.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "platform",
+						Description: "The platform of the account to use by default.",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+						Choices:     platformChoices(),
+					},
+				},
+			},
+		},
+	}
+}
+
+func AccountsHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	if subcommand := ctx.Options["default"]; subcommand != nil && len(subcommand.Options) > 0 {
+		return AccountsDefaultHandler(s, m, ctx, subcommand.Options[0].StringValue())
+	}
+
+	if ctx.Options["list"] != nil {
+		return AccountsListHandler(s, m, ctx)
+	}
+
+	return false, errors.New(ctx.Translate("commands.discord.accounts.errors.unknown_action", nil))
+}
+
+func AccountsListHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	accounts, err := services.DB.GetLinkedAccounts(ctx.User.ID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if len(accounts) == 0 {
+		return false, errors.New(ctx.Translate("commands.discord.accounts.errors.none", &map[string]interface{}{
+			"CommandName": ctx.Translate("commands.discord.link.name", nil),
+		}))
+	}
+
+	var fields []*discordgo.MessageEmbedField
+
+	for _, account := range accounts {
+		name := platformName(account.Platform)
+
+		if account.IsDefault {
+			name = ctx.Translate("commands.discord.accounts.list.default", &map[string]interface{}{
+				"Platform": name,
+			})
+		}
+
+		name = accountStatus(ctx, &account, name)
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: name,
+			Value: ctx.Translate("commands.discord.accounts.list.account", &map[string]interface{}{
+				"AccountName": account.Username,
+				"URL":         fmt.Sprintf("https://warframe.market/profile/%s", account.Username),
+				"Time":        services.LanguageManager.FormatRelative(&ctx.Locale, account.VerifiedAt),
+				"Reputation":  account.Reputation,
+			}),
+			Inline: true,
+		})
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:  ctx.Translate("commands.discord.accounts.list.title", nil),
+					Color:  constants.ThemeColor,
+					Fields: fields,
+					Footer: Footer(ctx.Locale),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func AccountsDefaultHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext, platform string) (bool, error) {
+	err := services.DB.SetDefaultAccount(ctx.User, platform)
+
+	if errors.Is(err, services.ErrAccountNotLinked) {
+		return false, errors.New(ctx.Translate("commands.discord.unlink.errors.not_linked", &map[string]interface{}{
+			"Platform": platformName(platform),
+		}))
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title: ctx.Translate("commands.discord.accounts.default.title", nil),
+					Description: ctx.Translate("commands.discord.accounts.default.description", &map[string]interface{}{
+						"AccountName": ctx.User.WfmUsername.String,
+						"Platform":    platformName(platform),
+					}),
+					Color:  constants.ThemeColor,
+					Footer: Footer(ctx.Locale),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func AccountsPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	return true, "", nil
+}
+
+// accountStatus adds to the name of the account whether it was banned, or can no longer be found under its name
+func accountStatus(ctx CommandContext, account *services.LinkedAccount, name string) string {
+	params := &map[string]interface{}{
+		"Platform": name,
+	}
+
+	if account.Banned {
+		name = ctx.Translate("commands.discord.accounts.list.banned", params)
+	} else if account.Stale {
+		name = ctx.Translate("commands.discord.accounts.list.stale", params)
+	}
+
+	return name
+}
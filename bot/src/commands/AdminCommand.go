@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The maximum length of an embed field value
+const embedFieldLimit = 1024
+
+func AdminCommand() Command {
+	return Command{
+		Name:        "admin",
+		Description: "Administrative actions for the bot staff.",
+		Usage:       "admin badge grant user: @VaporTrader badge: Developer",
+		Category:    "Administration",
+		Cooldown:    5 * time.Second,
+		Handler:     AdminHandler,
+		Permissions: AdminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "i18n",
+				Description: "Manage the language files.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "reload",
+						Description: "Reload every language file, and report any problems.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+					},
+				},
+			},
+			{
+				Name:        "badge",
+				Description: "Manage the badges which can't be earned.",
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "grant",
+						Description: "Award a badge to a user.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options:     badgeOptions(),
+					},
+					{
+						Name:        "revoke",
+						Description: "Take a badge away from a user.",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options:     badgeOptions(),
+					},
+				},
+			},
+		},
+	}
+}
+
+// badgeOptions are the options of the badge subcommands, offering every badge which can't be earned
+func badgeOptions() []*discordgo.ApplicationCommandOption {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+
+	for _, badge := range services.BadgeCatalog {
+		if badge.Achievable {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  badge.Icon + " " + badge.Name,
+			Value: badge.ID,
+		})
+	}
+
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "user",
+			Description: "The user to change the badges of.",
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Required:    true,
+		},
+		{
+			Name:        "badge",
+			Description: "The badge to award or take away.",
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Required:    true,
+			Choices:     choices,
+		},
+	}
+}
+
+func AdminHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	if group := ctx.Options["i18n"]; group != nil && len(group.Options) > 0 {
+		switch group.Options[0].Name {
+		case "reload":
+			return AdminI18nReloadHandler(s, m, ctx)
+		}
+	}
+
+	if group := ctx.Options["badge"]; group != nil && len(group.Options) > 0 {
+		return AdminBadgeHandler(s, m, ctx, group.Options[0])
+	}
+
+	return false, errors.New(ctx.Translate("commands.discord.admin.errors.unknown_action", nil))
+}
+
+func AdminBadgeHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) (bool, error) {
+	var userID string = ""
+	var badgeID int32 = 0
+
+	for _, option := range subcommand.Options {
+		switch option.Name {
+		case "user":
+			userID = option.UserValue(nil).ID
+		case "badge":
+			badgeID = int32(option.IntValue())
+		}
+	}
+
+	badge := services.GetBadge(badgeID)
+
+	// Earned badges are left to the achievement rules, so they always mean the same thing
+	if badge == nil || badge.Achievable {
+		return false, errors.New(ctx.Translate("commands.discord.admin.badge.errors.not_grantable", nil))
+	}
+
+	user, err := services.DB.GetUserByID(userID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if user == nil || user.ID == "" {
+		return false, errors.New(ctx.Translate("commands.discord.profile.errors.unknown_user", nil))
+	}
+
+	var changed bool
+
+	if subcommand.Name == "revoke" {
+		changed, err = services.DB.RevokeAward(user.ID, badgeID)
+	} else {
+		changed, err = services.DB.GrantAward(user.ID, badgeID)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	key := "commands.discord.admin.badge." + subcommand.Name
+
+	if !changed {
+		key += ".unchanged"
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: ctx.Translate(key, &map[string]interface{}{
+				"Badge": badge.Icon + " " + badge.Name,
+				"User":  "<@" + user.ID + ">",
+			}),
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func AdminI18nReloadHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	report, err := services.LanguageManager.Reload()
+
+	if err != nil {
+		return false, err
+	}
+
+	none := ctx.Translate("commands.discord.admin.i18n.reload.none", nil)
+
+	loaded := none
+
+	if len(report.Loaded) > 0 {
+		loaded = "`" + strings.Join(report.Loaded, "`, `") + "`"
+	}
+
+	failed := none
+
+	if len(report.Failed) > 0 {
+		var lines []string
+
+		for _, failure := range report.Failed {
+			lines = append(lines, failure.Err.Error())
+		}
+
+		failed = "```\n" + truncate(strings.Join(lines, "\n"), embedFieldLimit-8) + "\n```"
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   ctx.Translate("commands.discord.admin.i18n.reload.fields.loaded", nil),
+			Value:  truncate(loaded, embedFieldLimit),
+			Inline: false,
+		},
+		{
+			Name:   ctx.Translate("commands.discord.admin.i18n.reload.fields.failed", nil),
+			Value:  failed,
+			Inline: false,
+		},
+	}
+
+	for _, validation := range report.Validation {
+		value := ctx.Translate("commands.discord.admin.i18n.reload.clean", nil)
+
+		if !validation.IsClean() {
+			value = ctx.Translate("commands.discord.admin.i18n.reload.summary", &map[string]interface{}{
+				"Missing":    len(validation.Missing),
+				"Extra":      len(validation.Extra),
+				"Mismatched": len(validation.Mismatched),
+			}) + "\n```\n" + truncate(validation.String(), embedFieldLimit-200) + "\n```"
+		}
+
+		// Embeds are limited to 25 fields
+		if len(fields) >= 25 {
+			break
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   validation.Language,
+			Value:  value,
+			Inline: false,
+		})
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:  ctx.Translate("commands.discord.admin.i18n.reload.title", nil),
+					Color:  constants.ThemeColor,
+					Fields: fields,
+					Footer: Footer(ctx.Locale),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func AdminPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	if ctx.User.HasPermission("admin") {
+		return true, "", nil
+	}
+
+	return false, ctx.Translate("commands.discord.admin.errors.not_admin", nil), nil
+}
+
+// truncate shortens the text to at most limit characters, marking the cut with an ellipsis
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,331 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many items are ranked by /ducats rank
+const ducatsListSize = 15
+
+// How many lines of a pasted list are checked, as each may need a snapshot from warframe.market
+const ducatsPasteLimit = 20
+
+// The parts which can be filtered on, as named in the item slugs
+var ducatParts = []string{"blueprint", "neuroptics", "chassis", "systems", "barrel", "receiver", "stock", "blade", "handle", "grip", "string", "link", "pouch", "stars", "gauntlet", "ornament", "hilt", "head", "guard", "disc", "boot", "carapace", "cerebrum", "harness", "wings"}
+
+// Matches a quantity at the start or end of a pasted line, e.g. "3x Ash Prime Neuroptics" or "Ash Prime Chassis x2"
+var ducatQuantityPattern = regexp.MustCompile(`^(?i)(?:(\d+)\s*x?\s+(.+)|(.+?)\s+x?\s*(\d+))$`)
+
+// The lowest minimum liquidity, as options need a pointer to it
+var minDucatLiquidity float64 = 0
+
+func DucatsCommand() Command {
+	var parts []*discordgo.ApplicationCommandOptionChoice
+
+	for _, part := range ducatParts {
+		parts = append(parts, &discordgo.ApplicationCommandOptionChoice{
+			Name:  strings.ToUpper(part[:1]) + part[1:],
+			Value: part,
+		})
+	}
+
+	return Command{
+		Name:        "ducats",
+		Description: "Find the prime parts worth the most ducats for their price.",
+		Usage:       "ducats rank [vaulted: false] [part: neuroptics] [min_liquidity: 3]",
+		Category:    "Market",
+		Cooldown:    5 * time.Second,
+		Handler:     DucatsHandler,
+		Permissions: DucatsPermissions,
+		Modal:       DucatsModal,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "rank",
+				Description: "Rank the prime parts by ducats per platinum.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "vaulted",
+						Description: "Only rank vaulted parts, or only unvaulted ones.",
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Required:    false,
+					},
+					{
+						Name:        "part",
+						Description: "Only rank one kind of part.",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    false,
+						Choices:     parts,
+					},
+					{
+						Name:        "min_liquidity",
+						Description: "Only rank parts with at least this many for sale.",
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						MinValue:    &minDucatLiquidity,
+						Required:    false,
+					},
+				},
+			},
+			{
+				Name:        "check",
+				Description: "Paste a list of your parts, and find out which to sell and which to trade for ducats.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
+	}
+}
+
+func DucatsHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	if ctx.Options["check"] != nil {
+		err := s.InteractionRespond(m.Interaction, ducatsModal(ctx.Locale, ctx.User.ID))
+
+		return true, err
+	}
+
+	if subcommand := ctx.Options["rank"]; subcommand != nil {
+		return DucatsRankHandler(s, m, ctx, subcommand)
+	}
+
+	return false, errors.New(ctx.Translate("commands.discord.ducats.errors.unknown_action", nil))
+}
+
+func DucatsRankHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) (bool, error) {
+	filter := services.DucatFilter{}
+
+	for _, option := range subcommand.Options {
+		switch option.Name {
+		case "vaulted":
+			vaulted := option.BoolValue()
+			filter.Vaulted = &vaulted
+		case "part":
+			filter.Part = option.StringValue()
+		case "min_liquidity":
+			filter.MinLiquidity = int(option.IntValue())
+		}
+	}
+
+	values, err := services.DB.RankDucats(filter, ducatsListSize)
+
+	if err != nil {
+		return false, err
+	}
+
+	var lines []string
+
+	for index, value := range values {
+		lines = append(lines, ctx.Translate("commands.discord.ducats.rank.entry", ducatParams(ctx.Locale, index+1, &value)))
+	}
+
+	description := ctx.Translate("commands.discord.ducats.rank.none", nil)
+
+	if len(lines) > 0 {
+		description = strings.Join(lines, "\n")
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       ctx.Translate("commands.discord.ducats.rank.title", nil),
+					Description: truncate(description, 4096),
+					Color:       constants.ThemeColor,
+					Footer:      Footer(ctx.Locale),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func DucatsModal(s *discordgo.Session, m *discordgo.InteractionCreate, ctx ModalContext) (bool, error) {
+	translate := func(key string, params *map[string]interface{}) string {
+		return services.LanguageManager.Get(&ctx.Locale, "commands.discord.ducats.check."+key, params)
+	}
+
+	lines := strings.Split(ctx.Options["parts_field"], "\n")
+
+	if len(lines) > ducatsPasteLimit {
+		lines = lines[:ducatsPasteLimit]
+	}
+
+	// Parts without a recent snapshot are read from warframe.market, which can take a while behind the rate limit
+	err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	var results []string
+	var unknown []string
+
+	for _, line := range lines {
+		query, quantity := parseDucatLine(line)
+
+		if query == "" {
+			continue
+		}
+
+		item, err := ducatItem(query)
+
+		if err != nil {
+			unknown = append(unknown, query)
+			continue
+		}
+
+		err = services.Orders.Refresh(item.ID, item.Slug, services.DucatPlatform, services.OrderBookReconcileInterval)
+
+		if err != nil {
+			log.Printf("Error refreshing the order book of %s: %s", item.Slug, err)
+		}
+
+		// The parts are being sold, so they are priced against what buyers pay rather than the asking price
+		sale := services.GetDucatSale(*item, quantity)
+		params := ducatParams(ctx.Locale, 0, &services.DucatValue{Item: sale.Item, Ratio: sale.Ratio})
+		(*params)["Quantity"] = quantity
+		(*params)["TotalDucats"] = services.LanguageManager.FormatDucats(&ctx.Locale, int64(quantity)*int64(item.Ducats))
+		(*params)["TotalPrice"] = services.LanguageManager.FormatPlatinum(&ctx.Locale, int64(sale.Total))
+
+		key := "sell"
+
+		if !sale.Known() {
+			key = "unknown"
+		} else if sale.TradeForDucats() {
+			key = "ducats"
+		}
+
+		results = append(results, translate("entry."+key, params))
+	}
+
+	description := translate("none", nil)
+
+	if len(results) > 0 {
+		description = strings.Join(results, "\n")
+	}
+
+	if len(unknown) > 0 {
+		description += "\n\n" + translate("unknown", &map[string]interface{}{
+			"Items": strings.Join(unknown, ", "),
+		})
+	}
+
+	_, err = s.InteractionResponseEdit(m.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{
+			{
+				Title:       translate("title", nil),
+				Description: truncate(description, 4096),
+				Color:       constants.ThemeColor,
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: translate("threshold", &map[string]interface{}{
+						"Ratio": services.LanguageManager.FormatNumber(&ctx.Locale, services.DucatsPerPlatinumWorth, 0),
+					}),
+				},
+			},
+		},
+	})
+
+	return true, err
+}
+
+// ducatsModal asks the user to paste their parts, one per line
+func ducatsModal(locale string, userID string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "modals_ducats_" + userID,
+			Title:    services.LanguageManager.Get(&locale, "commands.discord.ducats.modal.title", nil),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "parts_field",
+							Label:       services.LanguageManager.Get(&locale, "commands.discord.ducats.modal.parts.label", nil),
+							Style:       discordgo.TextInputParagraph,
+							Placeholder: services.LanguageManager.Get(&locale, "commands.discord.ducats.modal.parts.placeholder", nil),
+							Required:    true,
+							MaxLength:   2000,
+							MinLength:   1,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// parseDucatLine splits a pasted line into the name of the item and how many the user has, one if not given
+func parseDucatLine(line string) (string, int) {
+	line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•"))
+
+	if match := ducatQuantityPattern.FindStringSubmatch(line); match != nil {
+		if match[1] != "" {
+			quantity, _ := strconv.Atoi(match[1])
+			return strings.TrimSpace(match[2]), max(quantity, 1)
+		}
+
+		quantity, _ := strconv.Atoi(match[4])
+		return strings.TrimSpace(match[3]), max(quantity, 1)
+	}
+
+	return line, 1
+}
+
+// ducatItem finds the prime part with the name, which must be worth ducats
+func ducatItem(query string) (*services.Item, error) {
+	match, err := services.Items.Find(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var item services.Item
+
+	err = services.DB.Inner.Where("id = ? AND ducats > 0", match.ItemID).First(&item).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// ducatParams are the parameters every description of a ducat value uses
+func ducatParams(locale string, position int, value *services.DucatValue) *map[string]interface{} {
+	name := services.Items.Name(value.Item.ID, &locale)
+
+	if name == "" {
+		name = value.Item.Slug
+	}
+
+	return &map[string]interface{}{
+		"Position":  position,
+		"Item":      name,
+		"URL":       fmt.Sprintf("https://warframe.market/items/%s", value.Item.Slug),
+		"Ducats":    services.LanguageManager.FormatDucats(&locale, int64(value.Item.Ducats)),
+		"Price":     services.LanguageManager.FormatPlatinum(&locale, int64(value.Price)),
+		"Ratio":     services.LanguageManager.FormatNumber(&locale, value.Ratio, 1),
+		"Liquidity": value.Liquidity,
+		"Vaulted":   strconv.FormatBool(value.Item.Vaulted),
+	}
+}
+
+func DucatsPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	return true, "", nil
+}
@@ -0,0 +1,272 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many opportunities are listed by /flips sets
+const flipsListSize = 10
+
+// The margin a set flip must make to be posted, when the guild doesn't choose one
+const defaultFlipMargin = 20
+
+func FlipsCommand() Command {
+	return Command{
+		Name:        "flips",
+		Description: "Find items which can be flipped for a profit.",
+		Usage:       "flips sets [min_liquidity: 2] [direction: build]",
+		Category:    "Market",
+		Cooldown:    5 * time.Second,
+		Handler:     FlipsHandler,
+		Permissions: FlipsPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "sets",
+				Description: "List the sets which are worth more or less than the sum of their parts.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "min_liquidity",
+						Description: "Only list flips which can be repeated at least this many times.",
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						MinValue:    &minFlipLiquidity,
+						Required:    false,
+					},
+					{
+						Name:        "direction",
+						Description: "Only list flips which build sets, or which split them.",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    false,
+						Choices:     flipDirectionChoices(),
+					},
+				},
+			},
+			{
+				Name:        "subscribe",
+				Description: "Post new profitable set flips in a channel of this server.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:         "channel",
+						Description:  "The channel to post the flips in.",
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+						Required:     true,
+					},
+					{
+						Name:        "min_margin",
+						Description: "The least platinum a flip must make to be posted.",
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						MinValue:    &minFlipMargin,
+						Required:    false,
+					},
+				},
+			},
+			{
+				Name:        "unsubscribe",
+				Description: "Stop posting set flips in this server.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+		},
+	}
+}
+
+// The lowest values of the options, as options need a pointer to them
+var minFlipLiquidity float64 = 0
+var minFlipMargin float64 = 1
+
+// flipDirectionChoices lists the ways a set can be flipped
+func flipDirectionChoices() []*discordgo.ApplicationCommandOptionChoice {
+	return []*discordgo.ApplicationCommandOptionChoice{
+		{
+			Name:              "Build",
+			NameLocalizations: services.LanguageManager.Localizations("commands.discord.flips.directions.build"),
+			Value:             services.SetBuild,
+		},
+		{
+			Name:              "Split",
+			NameLocalizations: services.LanguageManager.Localizations("commands.discord.flips.directions.split"),
+			Value:             services.SetSplit,
+		},
+	}
+}
+
+func FlipsHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	if subcommand := ctx.Options["subscribe"]; subcommand != nil {
+		return FlipsSubscribeHandler(s, m, ctx, subcommand)
+	}
+
+	if ctx.Options["unsubscribe"] != nil {
+		return FlipsUnsubscribeHandler(s, m, ctx)
+	}
+
+	if subcommand := ctx.Options["sets"]; subcommand != nil {
+		return FlipsSetsHandler(s, m, ctx, subcommand)
+	}
+
+	return false, errors.New(ctx.Translate("commands.discord.flips.errors.unknown_action", nil))
+}
+
+func FlipsSetsHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) (bool, error) {
+	var minLiquidity int = 0
+	var direction string = ""
+
+	for _, option := range subcommand.Options {
+		switch option.Name {
+		case "min_liquidity":
+			minLiquidity = int(option.IntValue())
+		case "direction":
+			direction = option.StringValue()
+		}
+	}
+
+	opportunities := services.Sets.Top(flipsListSize, minLiquidity, direction)
+	analyzed, total := services.Sets.Count()
+
+	var lines []string
+
+	for index, opportunity := range opportunities {
+		lines = append(lines, flipLine(ctx.Locale, index+1, &opportunity))
+	}
+
+	description := ctx.Translate("commands.discord.flips.sets.none", nil)
+
+	if len(lines) > 0 {
+		description = strings.Join(lines, "\n")
+	}
+
+	// The analyzer works through the sets slowly, so say how many have been looked at so far
+	description += "\n\n" + ctx.Translate("commands.discord.flips.sets.coverage", &map[string]interface{}{
+		"Analyzed": analyzed,
+		"Total":    total,
+	})
+
+	err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       ctx.Translate("commands.discord.flips.sets.title", nil),
+					Description: truncate(description, 4096),
+					Color:       constants.ThemeColor,
+					Footer:      Footer(ctx.Locale),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func FlipsSubscribeHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) (bool, error) {
+	var channel *discordgo.Channel = nil
+	var minMargin int = defaultFlipMargin
+
+	for _, option := range subcommand.Options {
+		switch option.Name {
+		case "channel":
+			channel = option.ChannelValue(nil)
+		case "min_margin":
+			minMargin = int(option.IntValue())
+		}
+	}
+
+	if channel == nil {
+		return false, errors.New(ctx.Translate("commands.discord.flips.errors.unknown_action", nil))
+	}
+
+	err := services.DB.SubscribeGuild(m.GuildID, services.SubscriptionSetFlips, channel.ID, minMargin)
+
+	if err != nil {
+		return false, err
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: ctx.Translate("commands.discord.flips.subscribe.done", &map[string]interface{}{
+				"Channel": "<#" + channel.ID + ">",
+				"Margin":  services.LanguageManager.FormatPlatinum(&ctx.Locale, int64(minMargin)),
+			}),
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func FlipsUnsubscribeHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	removed, err := services.DB.UnsubscribeGuild(m.GuildID, services.SubscriptionSetFlips)
+
+	if err != nil {
+		return false, err
+	}
+
+	key := "commands.discord.flips.unsubscribe.done"
+
+	if !removed {
+		key = "commands.discord.flips.unsubscribe.not_subscribed"
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: ctx.Translate(key, nil),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+// flipLine describes a single opportunity, e.g. "1. Ember Prime Set - buy the parts for 80, sell the set for 120: +40"
+func flipLine(locale string, position int, opportunity *services.SetOpportunity) string {
+	params := flipParams(locale, opportunity)
+	(*params)["Position"] = position
+
+	return services.LanguageManager.Get(&locale, "commands.discord.flips.sets.entry", params)
+}
+
+// flipParams are the parameters every description of an opportunity uses
+func flipParams(locale string, opportunity *services.SetOpportunity) *map[string]interface{} {
+	name := services.Items.Name(opportunity.SetID, &locale)
+
+	if name == "" {
+		name = opportunity.SetSlug
+	}
+
+	return &map[string]interface{}{
+		"Set":       name,
+		"URL":       fmt.Sprintf("https://warframe.market/items/%s", opportunity.SetSlug),
+		"Direction": opportunity.Direction,
+		"Cost":      services.LanguageManager.FormatPlatinum(&locale, int64(opportunity.Cost)),
+		"Revenue":   services.LanguageManager.FormatPlatinum(&locale, int64(opportunity.Revenue)),
+		"Margin":    services.LanguageManager.FormatPlatinum(&locale, int64(opportunity.Margin)),
+		"Liquidity": opportunity.Liquidity,
+	}
+}
+
+func FlipsPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	if ctx.Options["subscribe"] == nil && ctx.Options["unsubscribe"] == nil {
+		return true, "", nil
+	}
+
+	if m.GuildID == "" || m.Member == nil {
+		return false, ctx.Translate("commands.discord.flips.errors.guild_only", nil), nil
+	}
+
+	// Only the people who run the server decide what is posted in it
+	if m.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		return false, ctx.Translate("commands.discord.flips.errors.manage_guild", nil), nil
+	}
+
+	return true, "", nil
+}
@@ -26,27 +26,27 @@ func InfoHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx Comma
 		Data: &discordgo.InteractionResponseData{
 			Embeds: []*discordgo.MessageEmbed{
 				{
-					Title:       "Bot Information",
-					Description: "Vapor Trader is a bot which allows you to get information about the market value of Warframe items, as well as set up alerts for new orders which match your criteria.",
+					Title:       ctx.Translate("commands.discord.info.embed.title", nil),
+					Description: ctx.Translate("commands.discord.info.embed.description", nil),
 					Color:       constants.ThemeColor,
 					Fields: []*discordgo.MessageEmbedField{
 						{
-							Name:   "Name",
+							Name:   ctx.Translate("commands.discord.info.embed.fields.name", nil),
 							Value:  constants.BotName,
 							Inline: true,
 						},
 						{
-							Name:   "Version",
+							Name:   ctx.Translate("commands.discord.info.embed.fields.version", nil),
 							Value:  constants.Version,
 							Inline: true,
 						},
 						{
-							Name:   "Author",
+							Name:   ctx.Translate("commands.discord.info.embed.fields.author", nil),
 							Value:  constants.AuthorString,
 							Inline: false,
 						},
 					},
-					Footer: constants.Footer,
+					Footer: Footer(ctx.Locale),
 				},
 			},
 			Flags: discordgo.MessageFlagsEphemeral,
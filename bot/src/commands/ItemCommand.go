@@ -1,9 +1,14 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -12,7 +17,7 @@ func ItemCommand() Command {
 	return Command{
 		Name:         "market",
 		Description:  "Get information about a specific item or set.",
-		Usage:        "market [item: name] [set: name]",
+		Usage:        "market [item: name] [set: name] [platform: pc] [rank: 0] [online: true]",
 		Category:     "Utility",
 		Cooldown:     5 * time.Second,
 		Handler:      ItemHandler,
@@ -37,34 +42,72 @@ func ItemCommand() Command {
 				Name:        "platform",
 				Description: "The platform to get information about.",
 				Type:        discordgo.ApplicationCommandOptionString,
-				Choices: []*discordgo.ApplicationCommandOptionChoice{
-					{
-						Name:  "PC",
-						Value: "pc",
-					},
-					{
-						Name:  "Xbox (One, Series X/S)",
-						Value: "xbox",
-					},
-					{
-						Name:  "Playstation 4/5",
-						Value: "ps4",
-					},
-					{
-						Name:  "Nintendo Switch",
-						Value: "switch",
-					},
-				},
-				Required: false,
+				Choices:     platformChoices(),
+				Required:    false,
+			},
+			{
+				Name:        "rank",
+				Description: "The mod rank to get information about, every rank if not given.",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				MinValue:    &minModRank,
+				MaxValue:    10,
+				Required:    false,
+			},
+			{
+				Name:        "online",
+				Description: "Only show sellers who are online, the ones in game first.",
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Required:    false,
 			},
 		},
 	}
 }
 
+// The lowest rank a mod can have, as options need a pointer to it
+var minModRank float64 = 0
+
+// platformChoices lists every platform warframe.market supports
+func platformChoices() []*discordgo.ApplicationCommandOptionChoice {
+	return []*discordgo.ApplicationCommandOptionChoice{
+		{
+			Name:  "PC",
+			Value: "pc",
+		},
+		{
+			Name:  "Xbox (One, Series X/S)",
+			Value: "xbox",
+		},
+		{
+			Name:  "Playstation 4/5",
+			Value: "ps4",
+		},
+		{
+			Name:  "Nintendo Switch",
+			Value: "switch",
+		},
+	}
+}
+
+// platformName returns the display name of the platform
+func platformName(platform string) string {
+	for _, choice := range platformChoices() {
+		if choice.Value == platform {
+			return choice.Name
+		}
+	}
+
+	return platform
+}
+
+// How many prices are shown on each side of the order book
+const marketDepthLevels = 5
+
 func ItemHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
 	var item string = ""
 	var set string = ""
 	var platform string = "pc"
+	var rank *int = nil
+	var online bool = false
 
 	if itemOption := ctx.Options["item"]; itemOption != nil {
 		item = itemOption.StringValue()
@@ -74,7 +117,7 @@ func ItemHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx Comma
 		set = setOption.StringValue()
 
 		if item != "" {
-			return false, fmt.Errorf("You may not specify an item and a set at the same time.")
+			return false, errors.New(ctx.Translate("commands.discord.market.errors.item_and_set", nil))
 		}
 	}
 
@@ -82,43 +125,260 @@ func ItemHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx Comma
 		platform = platformOption.StringValue()
 	}
 
+	if rankOption := ctx.Options["rank"]; rankOption != nil {
+		value := int(rankOption.IntValue())
+		rank = &value
+	}
+
+	if onlineOption := ctx.Options["online"]; onlineOption != nil {
+		online = onlineOption.BoolValue()
+	}
+
 	if item == "" && set == "" {
-		return false, fmt.Errorf("You must specify an item to get information about.")
+		return false, errors.New(ctx.Translate("commands.discord.market.errors.no_item", nil))
+	}
+
+	match, err := marketItem(item, set)
+
+	if errors.Is(err, services.ErrItemNotFound) {
+		return false, errors.New(ctx.Translate("commands.discord.market.errors.not_found", &map[string]interface{}{
+			"Item": item + set,
+		}))
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	// Snapshots can take a few seconds behind the rate limit, which is longer than Discord waits for a response
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	err = services.Orders.Ensure(match.ItemID, match.Slug, platform)
+
+	if err != nil {
+		// A stale book is still better than nothing
+		log.Printf("Error reconciling the order book of %s on %s: %s", match.Slug, platform, err)
+	}
+
+	_, err = s.InteractionResponseEdit(m.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{marketEmbed(ctx, match, platform, rank, online)},
+	})
+
+	return true, err
+}
+
+// marketItem finds the item by its name, or the set if a set name was given instead
+func marketItem(item string, set string) (*services.ItemMatch, error) {
+	if item != "" {
+		return services.Items.Find(item)
+	}
+
+	matches, err := services.Items.Search(set, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Users often leave out the "Set" at the end of the name
+	if len(matches) == 0 || !strings.HasSuffix(matches[0].Slug, "_set") {
+		matches, err = services.Items.Search(set+" set", 0)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, match := range matches {
+		if strings.HasSuffix(match.Slug, "_set") {
+			return &match, nil
+		}
+	}
+
+	return nil, services.ErrItemNotFound
+}
+
+// marketEmbed shows the best prices and depth of the item from the order book.
+// When online is true, the sellers who can trade right now are listed instead of the sell depth.
+func marketEmbed(ctx CommandContext, match *services.ItemMatch, platform string, rank *int, online bool) *discordgo.MessageEmbed {
+	none := ctx.Translate("commands.discord.market.none", nil)
+
+	best := func(order *services.BookOrder) string {
+		if order == nil {
+			return none
+		}
+
+		return ctx.Translate("commands.discord.market.best", &map[string]interface{}{
+			"Price":    services.LanguageManager.FormatPlatinum(&ctx.Locale, int64(order.Price)),
+			"UserName": order.UserName,
+			"Quantity": order.Quantity,
+		})
+	}
+
+	depth := func(orderType string) string {
+		var lines []string
+
+		for _, level := range services.Orders.Depth(match.ItemID, platform, rank, orderType, marketDepthLevels) {
+			lines = append(lines, ctx.Translate("commands.discord.market.level", &map[string]interface{}{
+				"Price":    services.LanguageManager.FormatPlatinum(&ctx.Locale, int64(level.Price)),
+				"Quantity": level.Quantity,
+				"Count":    level.Orders,
+			}))
+		}
+
+		if len(lines) == 0 {
+			return none
+		}
+
+		return strings.Join(lines, "\n")
+	}
+
+	bestSell := services.Orders.BestAsk(match.ItemID, platform, rank)
+	sellField := &discordgo.MessageEmbedField{
+		Name:   ctx.Translate("commands.discord.market.fields.sell_depth", nil),
+		Value:  depth(string(services.OrderTypeSell)),
+		Inline: true,
 	}
 
-	if platform != "pc" {
-		return false, fmt.Errorf("Platform not supported")
+	if online {
+		sellers := services.Orders.Sellers(match.ItemID, platform, rank, true)
+		bestSell = nil
+
+		if len(sellers) > 0 {
+			bestSell = &sellers[0]
+		}
+
+		var lines []string
+
+		for _, seller := range sellers[:min(len(sellers), marketDepthLevels)] {
+			lines = append(lines, ctx.Translate("commands.discord.market.seller", &map[string]interface{}{
+				"Status":     seller.UserStatus,
+				"Price":      services.LanguageManager.FormatPlatinum(&ctx.Locale, int64(seller.Price)),
+				"UserName":   seller.UserName,
+				"Reputation": seller.Reputation,
+			}))
+		}
+
+		sellField.Name = ctx.Translate("commands.discord.market.fields.online_sellers", nil)
+		sellField.Value = none
+
+		if len(lines) > 0 {
+			sellField.Value = strings.Join(lines, "\n")
+		}
+	}
+
+	name := services.Items.Name(match.ItemID, &ctx.Locale)
+
+	if name == "" {
+		name = match.Name
 	}
 
-	// Fetch item stats from the api
+	if rank != nil {
+		name = ctx.Translate("commands.discord.market.rank", &map[string]interface{}{
+			"Item": name,
+			"Rank": *rank,
+		})
+	}
 
-	// tx := services.DB.Inner.Find(&item, "", itemId)
+	_, filteredSells := services.Orders.Clean(match.ItemID, platform, rank, string(services.OrderTypeSell))
+	_, filteredBuys := services.Orders.Clean(match.ItemID, platform, rank, string(services.OrderTypeBuy))
 
-	// services.API.GetItemStats(, platform)
+	updated := none
 
-	return true, nil
+	if reconciledAt := services.Orders.ReconciledAt(match.ItemID, platform); !reconciledAt.IsZero() {
+		updated = services.LanguageManager.FormatRelative(&ctx.Locale, reconciledAt)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: name + " - " + platformName(platform),
+		URL:   fmt.Sprintf("https://warframe.market/items/%s", match.Slug),
+		Color: constants.ThemeColor,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   ctx.Translate("commands.discord.market.fields.best_sell", nil),
+				Value:  best(bestSell),
+				Inline: true,
+			},
+			{
+				Name:   ctx.Translate("commands.discord.market.fields.best_buy", nil),
+				Value:  best(services.Orders.BestBid(match.ItemID, platform, rank)),
+				Inline: true,
+			},
+			{
+				Name:   ctx.Translate("commands.discord.market.fields.updated", nil),
+				Value:  updated,
+				Inline: true,
+			},
+			sellField,
+			{
+				Name:   ctx.Translate("commands.discord.market.fields.buy_depth", nil),
+				Value:  depth(string(services.OrderTypeBuy)),
+				Inline: true,
+			},
+			{
+				Name:   ctx.Translate("commands.discord.market.fields.filtered", nil),
+				Value:  strconv.Itoa(filteredSells + filteredBuys),
+				Inline: true,
+			},
+		},
+		Footer: Footer(ctx.Locale),
+	}
 }
 
 func ItemPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
 	return true, "", nil
 }
 
+// The most choices Discord shows for an autocomplete
+const autocompleteLimit = 25
+
 func ItemAutocomplete(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	var focused *discordgo.ApplicationCommandInteractionDataOption = nil
 
-	switch {
-	case ctx.Options["item"].Focused:
+	for _, option := range ctx.Options {
+		if option.Focused {
+			focused = option
+		}
+	}
 
-	case ctx.Options["set"].Focused:
+	if focused == nil {
+		return false, nil
+	}
 
-	case ctx.Options["platform"].Focused:
+	matches, err := services.Items.Search(focused.StringValue(), 0)
 
+	if err != nil {
+		return false, err
 	}
 
-	log.Printf("Autocomplete: %v", ctx.Options["item"].StringValue())
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
 
-	if ctx.Options["item"].StringValue() == "" {
-		return false, nil
+	for _, match := range matches {
+		if focused.Name == "set" && !strings.HasSuffix(match.Slug, "_set") {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncate(match.Name, 100),
+			Value: truncate(match.Name, 100),
+		})
+
+		if len(choices) == autocompleteLimit {
+			break
+		}
 	}
 
-	return true, nil
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+
+	return true, err
 }
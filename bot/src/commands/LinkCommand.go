@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"time"
 	"vaportrader/src/constants"
+	"vaportrader/src/linking"
 	"vaportrader/src/services"
 
 	"github.com/bwmarrin/discordgo"
@@ -31,307 +33,501 @@ func LinkCommand() Command {
 	}
 }
 
-type AccountLinkStatus struct {
-	ID          string
-	Phase       uint8
-	Code        string
-	Username    *string
-	Profile     *services.ApiProfile
-	Interaction *discordgo.Interaction
-}
-
 type LinkModalResponse struct {
 	Username string `json:"username_field"`
 }
 
 func LinkAction(s *discordgo.Session, m *discordgo.InteractionCreate, ctx ActionContext) (bool, error) {
-	if ctx.Action.CustomID == "link_account_wfm_"+ctx.User.ID+"_accept" {
+	session, err := linking.Sessions.Active(ctx.User.ID)
 
-		rawEntry := services.KV.Get("link_account_wfm_" + ctx.User.ID)
+	if errors.Is(err, linking.ErrNoSession) {
+		return false, errors.New(ctx.Translate("commands.discord.link.errors.missing", &map[string]interface{}{
+			"UserID": ctx.User.ID,
+		}))
+	}
 
-		if rawEntry != nil {
-			entry := rawEntry.Value.(AccountLinkStatus)
+	if err != nil {
+		return false, err
+	}
 
-			if entry.Phase == 1 {
-				entry.Phase = 2
+	switch ctx.Action.CustomID {
+	case "link_account_wfm_" + ctx.User.ID + "_accept":
+		err = linking.Sessions.ConfirmProfile(session)
 
-				s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseDeferredMessageUpdate,
-				})
+		if err != nil {
+			return false, errors.New(ctx.Translate("commands.discord.link.errors.invalid_state", nil))
+		}
 
-				_, err := s.InteractionResponseEdit(entry.Interaction, &discordgo.WebhookEdit{
-					Embeds: &[]*discordgo.MessageEmbed{
-						{
-							Title:       "Here's your code!",
-							Description: "Send this code to the [Vapor Trader](https://warframe.market/profile/VaporTrader) account as a private message. This will automatically link your Discord, and Warframe Market accounts",
-							Color:       constants.ThemeColor,
-							Fields: []*discordgo.MessageEmbedField{
-								{
-									Name:   "Code",
-									Value:  entry.Code,
-									Inline: true,
-								},
-								{
-									Name: "Expires",
-									// Show the expiry time as a relative time
-									Value:  rawEntry.Expiry.Format("in 2 minutes"),
-									Inline: true,
-								},
-								{
-									Name:  "Usage",
-									Value: "```\nlink " + entry.Code + "\n```",
-								},
-							},
-						},
-					},
-					Components: &[]discordgo.MessageComponent{
-						discordgo.ActionsRow{
-							Components: []discordgo.MessageComponent{
-								discordgo.Button{
-									Label:    "Take me there",
-									Style:    discordgo.LinkButton,
-									URL:      "https://warframe.market/profile/VaporTrader",
-									Disabled: false,
-								},
-							},
-						},
-					},
-				})
+		err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: methodMessage(ctx),
+		})
 
-				entry.Interaction = m.Interaction
+		if err != nil {
+			return false, err
+		}
+	case "link_account_wfm_" + ctx.User.ID + "_reject":
+		err = linking.Sessions.RejectProfile(session)
 
-				if err != nil {
-					println(err.Error())
-					return false, err
-				}
+		if err != nil {
+			return false, errors.New(ctx.Translate("commands.discord.link.errors.invalid_state", nil))
+		}
+
+		s.InteractionResponseDelete(m.Interaction)
 
-				services.KV.Set("link_account_wfm_"+ctx.User.ID, entry, time.Minute*15)
+		err = s.InteractionRespond(m.Interaction, usernameModal(ctx.User.ID, ctx.Locale, session.Username.String))
 
-			} else {
-				services.KV.Delete("link_account_wfm_" + ctx.User.ID)
-				return false, fmt.Errorf("Link request in invalid state - Clearing")
-			}
+		if err != nil {
+			return false, err
 		}
-	} else if ctx.Action.CustomID == "link_account_wfm_"+ctx.User.ID+"_reject" {
-		rawEntry := services.KV.Get("link_account_wfm_" + ctx.User.ID)
-
-		if rawEntry != nil {
-			entry := rawEntry.Value.(AccountLinkStatus)
-
-			s.InteractionResponseDelete(m.Interaction)
-			err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseModal,
-				Data: &discordgo.InteractionResponseData{
-					CustomID: "modals_link_account_wfm_" + ctx.User.ID,
-					Title:    "Link your Warframe Market account",
-					Components: []discordgo.MessageComponent{
-						discordgo.ActionsRow{
-							Components: []discordgo.MessageComponent{
-								discordgo.TextInput{
-									CustomID:    "username_field",
-									Label:       "What is your Warframe Market username?",
-									Style:       discordgo.TextInputShort,
-									Placeholder: "(Case sensitive) VaporTrader",
-									Value:       *entry.Username,
-									Required:    true,
-									MaxLength:   60,
-									MinLength:   1,
-								},
-							},
-						},
-					},
-				},
-			})
-
-			if err != nil {
-				println(err.Error())
-				return false, err
-			}
-
-			entry.Phase = 0
-			entry.Profile = nil
-			entry.Username = nil
-			services.KV.Set("link_account_wfm_"+ctx.User.ID, entry, time.Minute*15)
-		} else {
-			return false, fmt.Errorf("No Link entry found for user %s - Somehow you broke it?\nPlease report this incident to the developer.", ctx.User.ID)
+	case "link_account_wfm_" + ctx.User.ID + "_method_message":
+		err = linking.Sessions.ChooseMethod(session, linking.MethodMessage, m.Interaction)
+
+		if err != nil {
+			return false, errors.New(ctx.Translate("commands.discord.link.errors.invalid_state", nil))
+		}
+
+		err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: codeMessage(ctx, session),
+		})
+
+		if err != nil {
+			return false, err
+		}
+	case "link_account_wfm_" + ctx.User.ID + "_method_profile":
+		err = linking.Sessions.ChooseMethod(session, linking.MethodProfile, m.Interaction)
+
+		if err != nil {
+			return false, errors.New(ctx.Translate("commands.discord.link.errors.invalid_state", nil))
+		}
+
+		err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: profileMessage(ctx, session),
+		})
+
+		if err != nil {
+			return false, err
 		}
+	case "link_account_wfm_" + ctx.User.ID + "_verify":
+		return verifyProfile(s, m, ctx, session)
+	case "link_account_wfm_" + ctx.User.ID + "_cancel":
+		err = linking.Sessions.Cancel(session)
 
+		if err != nil {
+			return false, errors.New(ctx.Translate("commands.discord.link.errors.invalid_state", nil))
+		}
+
+		err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "",
+				Embeds:     []*discordgo.MessageEmbed{linkResultEmbed(session, "cancelled", ctx.Locale)},
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+
+		if err != nil {
+			return false, err
+		}
 	}
 
 	return true, nil
 }
 
+// verifyProfile completes the link once the session's token shows up in the "about" text of the chosen profile
+func verifyProfile(s *discordgo.Session, m *discordgo.InteractionCreate, ctx ActionContext, session *services.LinkSession) (bool, error) {
+	params := &map[string]interface{}{
+		"AccountName": session.ProfileName.String,
+		"CommandName": ctx.Translate("commands.discord.link.name", nil),
+	}
+
+	profile, err := services.API.GetUser(session.ProfileName.String)
+
+	if err != nil {
+		return false, err
+	}
+
+	// The name may have been given up, and taken by someone else since
+	if profile.ID != session.ProfileID.String {
+		return false, errors.New(ctx.Translate("commands.discord.link.profile.errors.mismatch", params))
+	}
+
+	if !linking.HasProfileToken(session, profile) {
+		return false, errors.New(ctx.Translate("commands.discord.link.profile.errors.missing_token", params))
+	}
+
+	linkCode, err := services.VerifyLinkCode(session.Code.String, profile.ID)
+
+	var locked *services.LinkLockedError
+
+	switch {
+	case errors.As(err, &locked):
+		return false, errors.New(ctx.Translate("commands.discord.link.profile.errors.locked", &map[string]interface{}{
+			"Time": services.LanguageManager.FormatRelative(&ctx.Locale, locked.Until),
+		}))
+	case errors.Is(err, services.ErrLinkCodeExpired), errors.Is(err, services.ErrLinkCodeInvalid), errors.Is(err, services.ErrLinkCodeUsed):
+		return false, errors.New(ctx.Translate("commands.discord.link.result.expired.description", params))
+	case err != nil:
+		return false, err
+	}
+
+	// Swap in this interaction, so the result can be shown on the message even if the original token has expired
+	err = linking.Sessions.ChooseMethod(session, linking.MethodProfile, m.Interaction)
+
+	if err != nil {
+		return false, errors.New(ctx.Translate("commands.discord.link.errors.invalid_state", nil))
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	// The link notifier shows the result on the message
+	_, err = linking.Sessions.Complete(session, linkCode, profile.ID)
+
+	if err == nil {
+		return true, nil
+	}
+
+	var content string
+
+	switch {
+	case errors.Is(err, services.ErrAccountAlreadyLinked):
+		content = ctx.Translate("commands.discord.link.profile.errors.taken", params)
+	case errors.Is(err, services.ErrPlatformAlreadyLinked):
+		content = ctx.Translate("commands.discord.link.errors.already_linked", &map[string]interface{}{
+			"Platform": platformName(session.ProfilePlatform.String),
+		})
+	default:
+		content = ctx.Translate("commands.handler.errors.generic.failed", &map[string]interface{}{
+			"Error": err.Error(),
+		})
+	}
+
+	// The interaction was already answered, so the error has to follow up on it
+	_, _ = s.FollowupMessageCreate(m.Interaction, true, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+
+	return false, nil
+}
+
+// methodMessage asks the user how they want to prove they own the profile
+func methodMessage(ctx ActionContext) *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       ctx.Translate("commands.discord.link.method.title", nil),
+				Description: ctx.Translate("commands.discord.link.method.description", nil),
+				Color:       constants.ThemeColor,
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						CustomID: "link_account_wfm_" + ctx.User.ID + "_method_message",
+						Label:    ctx.Translate("commands.discord.link.method.buttons.message", nil),
+						Style:    discordgo.PrimaryButton,
+					},
+					discordgo.Button{
+						CustomID: "link_account_wfm_" + ctx.User.ID + "_method_profile",
+						Label:    ctx.Translate("commands.discord.link.method.buttons.profile", nil),
+						Style:    discordgo.SecondaryButton,
+					},
+					cancelLinkButton(ctx.User.ID, ctx.Locale),
+				},
+			},
+		},
+	}
+}
+
+// codeMessage shows the code the user sends to the bot as a private message
+func codeMessage(ctx ActionContext, session *services.LinkSession) *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       ctx.Translate("commands.discord.link.code.title", nil),
+				Description: ctx.Translate("commands.discord.link.code.description", nil),
+				Color:       constants.ThemeColor,
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:   ctx.Translate("commands.discord.link.code.fields.code", nil),
+						Value:  session.Code.String,
+						Inline: true,
+					},
+					{
+						Name: ctx.Translate("commands.discord.link.code.fields.expires", nil),
+						// Show the expiry time as a relative time
+						Value:  services.LanguageManager.FormatRelative(&ctx.Locale, session.ExpiresAt),
+						Inline: true,
+					},
+					{
+						Name:  ctx.Translate("commands.discord.link.code.fields.usage", nil),
+						Value: "```\nlink " + session.Code.String + "\n```",
+					},
+				},
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    ctx.Translate("commands.discord.link.code.buttons.open", nil),
+						Style:    discordgo.LinkButton,
+						URL:      "https://warframe.market/profile/VaporTrader",
+						Disabled: false,
+					},
+					discordgo.Button{
+						CustomID: "link_account_wfm_" + ctx.User.ID + "_method_profile",
+						Label:    ctx.Translate("commands.discord.link.code.buttons.profile", nil),
+						Style:    discordgo.SecondaryButton,
+					},
+					cancelLinkButton(ctx.User.ID, ctx.Locale),
+				},
+			},
+		},
+	}
+}
+
+// profileMessage shows the token the user pastes into the "about" text of their profile
+func profileMessage(ctx ActionContext, session *services.LinkSession) *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title: ctx.Translate("commands.discord.link.profile.title", nil),
+				Description: ctx.Translate("commands.discord.link.profile.description", &map[string]interface{}{
+					"AccountName": session.ProfileName.String,
+				}),
+				Color: constants.ThemeColor,
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:   ctx.Translate("commands.discord.link.profile.fields.token", nil),
+						Value:  "```\n" + linking.ProfileToken(session) + "\n```",
+						Inline: true,
+					},
+					{
+						Name:   ctx.Translate("commands.discord.link.code.fields.expires", nil),
+						Value:  services.LanguageManager.FormatRelative(&ctx.Locale, session.ExpiresAt),
+						Inline: true,
+					},
+				},
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						CustomID: "link_account_wfm_" + ctx.User.ID + "_verify",
+						Label:    ctx.Translate("commands.discord.link.profile.buttons.verify", nil),
+						Style:    discordgo.SuccessButton,
+					},
+					discordgo.Button{
+						Label: ctx.Translate("commands.discord.link.profile.buttons.open", nil),
+						Style: discordgo.LinkButton,
+						URL:   fmt.Sprintf("https://warframe.market/profile/%s", session.ProfileName.String),
+					},
+					discordgo.Button{
+						CustomID: "link_account_wfm_" + ctx.User.ID + "_method_message",
+						Label:    ctx.Translate("commands.discord.link.profile.buttons.message", nil),
+						Style:    discordgo.SecondaryButton,
+					},
+					cancelLinkButton(ctx.User.ID, ctx.Locale),
+				},
+			},
+		},
+	}
+}
+
 func LinkModal(s *discordgo.Session, m *discordgo.InteractionCreate, ctx ModalContext) (bool, error) {
 	username := ctx.Options["username_field"]
 
-	rawEntry := services.KV.Get("link_account_wfm_" + ctx.User.ID)
+	session, err := linking.Sessions.Active(ctx.User.ID)
 
-	if rawEntry != nil {
-		entry := rawEntry.Value.(AccountLinkStatus)
+	if errors.Is(err, linking.ErrNoSession) {
+		return false, errors.New(ctx.Translate("commands.discord.link.errors.missing", &map[string]interface{}{
+			"UserID": ctx.User.ID,
+		}))
+	}
 
-		if entry.Phase == 0 {
-			entry.Phase = 1
+	if err != nil {
+		return false, err
+	}
+
+	profile, err := services.API.GetUser(username)
 
-			if entry.Username == nil {
-				entry.Username = &username
-			}
+	if err != nil {
+		return false, err
+	}
 
-			if entry.Profile == nil {
-				apiProfile, err := services.API.GetUser(*entry.Username)
+	linked, err := services.DB.GetLinkedAccount(ctx.User.ID, profile.Platform)
+
+	if err != nil {
+		return false, err
+	}
 
-				if err != nil {
-					return false, err
-				}
+	if linked != nil {
+		return false, errors.New(ctx.Translate("commands.discord.link.errors.already_linked", &map[string]interface{}{
+			"Platform": platformName(linked.Platform),
+		}))
+	}
 
-				entry.Profile = apiProfile
-			}
+	err = linking.Sessions.SubmitProfile(session, username, profile, m.Interaction)
 
-			var thumbnail *discordgo.MessageEmbedThumbnail = nil
+	if err != nil {
+		return false, errors.New(ctx.Translate("commands.discord.link.errors.invalid_state", nil))
+	}
 
-			if entry.Profile.Avatar != nil {
-				thumbnail = &discordgo.MessageEmbedThumbnail{
-					URL: "https://warframe.market/static/assets/" + *entry.Profile.Avatar,
-				}
-			}
+	var thumbnail *discordgo.MessageEmbedThumbnail = nil
 
-			entry.Interaction = m.Interaction
+	if profile.Avatar != nil {
+		thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: "https://warframe.market/static/assets/" + *profile.Avatar,
+		}
+	}
 
-			var banText string = "No"
+	var banText string = ctx.Translate("commands.discord.link.confirm.no", nil)
 
-			if entry.Profile.Banned {
-				banText = "Yes"
-			}
+	if profile.Banned {
+		banText = ctx.Translate("commands.discord.link.confirm.yes", nil)
+	}
 
-			services.KV.Set("link_account_wfm_"+ctx.User.ID, entry, time.Minute*15)
-			err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Embeds: []*discordgo.MessageEmbed{
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title: ctx.Translate("commands.discord.link.confirm.title", nil),
+					Color: constants.ThemeColor,
+					URL:   fmt.Sprintf("https://warframe.market/profile/%s", profile.IngameName),
+					Fields: []*discordgo.MessageEmbedField{
 						{
-							Title: "Is this your Warframe Market account?",
-							Color: constants.ThemeColor,
-							URL:   fmt.Sprintf("https://warframe.market/profile/%s", entry.Profile.IngameName),
-							Fields: []*discordgo.MessageEmbedField{
-								{
-									Name:   "Username",
-									Value:  entry.Profile.IngameName,
-									Inline: true,
-								},
-								{
-									Name:   "Status",
-									Value:  fmt.Sprintf("**%s**", entry.Profile.Status),
-									Inline: true,
-								},
-								{
-									Name:   "Banned",
-									Value:  banText,
-									Inline: true,
-								},
-							},
-							Thumbnail: thumbnail,
+							Name:   ctx.Translate("commands.discord.link.confirm.fields.username", nil),
+							Value:  profile.IngameName,
+							Inline: true,
+						},
+						{
+							Name:   ctx.Translate("commands.discord.link.confirm.fields.status", nil),
+							Value:  fmt.Sprintf("**%s**", profile.Status),
+							Inline: true,
+						},
+						{
+							Name:   ctx.Translate("commands.discord.link.confirm.fields.banned", nil),
+							Value:  banText,
+							Inline: true,
 						},
 					},
+					Thumbnail: thumbnail,
+				},
+			},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
 					Components: []discordgo.MessageComponent{
-						discordgo.ActionsRow{
-							Components: []discordgo.MessageComponent{
-								discordgo.Button{
-									CustomID: "link_account_wfm_" + ctx.User.ID + "_accept",
-									Label:    "Yes",
-									Style:    discordgo.SuccessButton,
-									Disabled: false,
-								},
-								discordgo.Button{
-									CustomID: "link_account_wfm_" + ctx.User.ID + "_reject",
-									Label:    "No",
-									Style:    discordgo.DangerButton,
-									Disabled: false,
-								},
-							},
+						discordgo.Button{
+							CustomID: "link_account_wfm_" + ctx.User.ID + "_accept",
+							Label:    ctx.Translate("commands.discord.link.confirm.yes", nil),
+							Style:    discordgo.SuccessButton,
+							Disabled: false,
 						},
+						discordgo.Button{
+							CustomID: "link_account_wfm_" + ctx.User.ID + "_reject",
+							Label:    ctx.Translate("commands.discord.link.confirm.no", nil),
+							Style:    discordgo.DangerButton,
+							Disabled: false,
+						},
+						cancelLinkButton(ctx.User.ID, ctx.Locale),
 					},
-					Flags: discordgo.MessageFlagsEphemeral,
 				},
-			})
-
-			if err != nil {
-				return false, err
-			}
-		}
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
 
-	} else {
-		return false, fmt.Errorf("No Link entry found for user %s - Somehow you broke it?\nPlease report this incident to the developer.", ctx.User.ID)
+	if err != nil {
+		return false, err
 	}
 
 	return true, nil
 }
 
 func LinkHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	_, err := linking.Sessions.Start(ctx.User.ID)
 
-	rawEntry := services.KV.Get("link_account_wfm_" + ctx.User.ID)
-
-	if rawEntry == nil {
-		code, err := services.GenerateOTP(ctx.User.ID)
-
-		println("Generated code: " + code)
-
-		if err != nil {
-			return false, err
-		}
-
-		services.KV.Set(code+":totp", "link_account_wfm_"+ctx.User.ID, time.Hour)
-
-		entry := services.KV.Set("link_account_wfm_"+ctx.User.ID, AccountLinkStatus{
-			ID:    ctx.User.ID,
-			Phase: 0,
-			Code:  code,
-		}, time.Minute*15)
-
+	if errors.Is(err, linking.ErrSessionActive) {
+		// The message showing the other attempt may be gone, so offer to cancel it from here
 		err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseModal,
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				CustomID: "modals_link_account_wfm_" + ctx.User.ID,
-				Title:    "Link your Warframe Market account",
+				Content: ctx.Translate("commands.discord.link.errors.active", nil),
 				Components: []discordgo.MessageComponent{
 					discordgo.ActionsRow{
-						Components: []discordgo.MessageComponent{
-							discordgo.TextInput{
-								CustomID:    "username_field",
-								Label:       "What is your Warframe Market username?",
-								Style:       discordgo.TextInputShort,
-								Placeholder: "(Case sensitive) VaporTrader",
-								Required:    true,
-								MaxLength:   60,
-								MinLength:   1,
-							},
-						},
+						Components: []discordgo.MessageComponent{cancelLinkButton(ctx.User.ID, ctx.Locale)},
 					},
 				},
+				Flags: discordgo.MessageFlagsEphemeral,
 			},
 		})
 
-		if err != nil {
-			println(err.Error())
-			return false, err
-		}
+		return true, err
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	err = s.InteractionRespond(m.Interaction, usernameModal(ctx.User.ID, ctx.Locale, ""))
 
-		println("Initialized new link request - " + entry.Value.(AccountLinkStatus).Code)
-	} else {
-		// user is already linking an account
-		return false, fmt.Errorf("You aready have an active link attempt. Please complete that first (or wait for 15 minutes for it to expire).")
+	if err != nil {
+		return false, err
 	}
 
 	return true, nil
 }
 
-func LinkPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
-
-	if !ctx.User.WfmID.Valid {
-		return true, "", nil
+// cancelLinkButton ends the user's link attempt, so they can start again
+func cancelLinkButton(userID string, locale string) discordgo.Button {
+	return discordgo.Button{
+		CustomID: "link_account_wfm_" + userID + "_cancel",
+		Label:    services.LanguageManager.Get(&locale, "commands.discord.link.buttons.cancel", nil),
+		Style:    discordgo.SecondaryButton,
 	}
+}
 
-	if ctx.User.WfmID.Valid {
-		return false, "You already have a Warframe Market account linked to this bot\nIf you wish to link another account, please use `/unlink` to invalidate the linked account.", nil
+// usernameModal asks the user for their warframe.market username, optionally filled in with their previous answer
+func usernameModal(userID string, locale string, username string) *discordgo.InteractionResponse {
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "modals_link_account_wfm_" + userID,
+			Title:    services.LanguageManager.Get(&locale, "commands.discord.link.modal.title", nil),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "username_field",
+							Label:       services.LanguageManager.Get(&locale, "commands.discord.link.modal.username.label", nil),
+							Style:       discordgo.TextInputShort,
+							Placeholder: services.LanguageManager.Get(&locale, "commands.discord.link.modal.username.placeholder", nil),
+							Value:       username,
+							Required:    true,
+							MaxLength:   60,
+							MinLength:   1,
+						},
+					},
+				},
+			},
+		},
 	}
+}
 
-	return false, "This error shouldn't happen, as it indicates a corrupted database.", nil
+func LinkPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	// Users may link one account per platform, which is checked once they choose a profile
+	return true, "", nil
 }
@@ -0,0 +1,350 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How many orders are shown on each page of a lookup
+const lookupPageSize = 8
+
+func LookupCommand() Command {
+	return Command{
+		Name:        "lookup",
+		Description: "Look up a Warframe Market user, and their active orders.",
+		Usage:       "lookup user: VaporTrader",
+		Category:    "Utility",
+		Cooldown:    5 * time.Second,
+		Handler:     LookupHandler,
+		Permissions: LookupPermissions,
+		Action:      LookupAction,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "user",
+				Description: "The in-game name of the user to look up.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    true,
+				MaxLength:   60,
+			},
+		},
+	}
+}
+
+func LookupHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	username := strings.TrimSpace(ctx.Options["user"].StringValue())
+
+	// The profile and its orders are two requests behind the rate limit, which can be longer than Discord waits for a response
+	err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	data, err := lookupMessage(ctx.Locale, ctx.User.ID, username, 0)
+
+	return lookupEdit(s, m, ctx.Locale, data, err)
+}
+
+func LookupAction(s *discordgo.Session, m *discordgo.InteractionCreate, ctx ActionContext) (bool, error) {
+	// The custom ID is "lookup_<user>_<prev|next|whisper>_<page>_<username>"
+	parts := strings.SplitN(strings.TrimPrefix(ctx.Action.CustomID, "lookup_"+ctx.User.ID+"_"), "_", 3)
+
+	if len(parts) != 3 {
+		return false, errors.New(ctx.Translate("commands.handler.errors.unknown", nil))
+	}
+
+	action, username := parts[0], parts[2]
+	page, err := strconv.Atoi(parts[1])
+
+	if err != nil {
+		return false, errors.New(ctx.Translate("commands.handler.errors.unknown", nil))
+	}
+
+	switch action {
+	case "prev", "next":
+		if action == "prev" {
+			page--
+		} else {
+			page++
+		}
+
+		err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+
+		if err != nil {
+			return false, err
+		}
+
+		data, err := lookupMessage(ctx.Locale, ctx.User.ID, username, page)
+
+		return lookupEdit(s, m, ctx.Locale, data, err)
+	case "whisper":
+		if len(ctx.Action.Values) == 0 {
+			return false, errors.New(ctx.Translate("commands.handler.errors.unknown", nil))
+		}
+
+		return lookupWhisper(s, m, ctx, username, ctx.Action.Values[0])
+	}
+
+	return false, errors.New(ctx.Translate("commands.handler.errors.unknown", nil))
+}
+
+// lookupWhisper replies with the in-game whisper answering the chosen order
+func lookupWhisper(s *discordgo.Session, m *discordgo.InteractionCreate, ctx ActionContext, username string, orderID string) (bool, error) {
+	err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	profile, err := services.API.GetUser(username)
+
+	if err != nil {
+		return lookupEdit(s, m, ctx.Locale, nil, lookupError(ctx.Locale, username, err))
+	}
+
+	orders, err := services.API.GetUserOrders(profile.IngameName)
+
+	if err != nil {
+		return lookupEdit(s, m, ctx.Locale, nil, err)
+	}
+
+	for _, order := range orders {
+		if order.ID != orderID {
+			continue
+		}
+
+		whisper := services.Whisper(profile.IngameName, order.OrderType, order.Item.En.ItemName, order.ModRank, order.Platinum)
+
+		return lookupEdit(s, m, ctx.Locale, &discordgo.InteractionResponseData{
+			Content: ctx.Translate("commands.discord.lookup.whisper", nil) + "\n```\n" + whisper + "\n```",
+		}, nil)
+	}
+
+	return lookupEdit(s, m, ctx.Locale, nil, errors.New(ctx.Translate("commands.discord.lookup.errors.order_gone", nil)))
+}
+
+// lookupEdit fills in the deferred response, or follows up with why it couldn't be
+func lookupEdit(s *discordgo.Session, m *discordgo.InteractionCreate, locale string, data *discordgo.InteractionResponseData, err error) (bool, error) {
+	if err != nil {
+		// The interaction was already answered, so the error has to follow up on it
+		_, err = s.FollowupMessageCreate(m.Interaction, true, &discordgo.WebhookParams{
+			Content: services.LanguageManager.Get(&locale, "commands.handler.errors.generic.failed", &map[string]interface{}{
+				"Error": err.Error(),
+			}),
+			Flags: discordgo.MessageFlagsEphemeral,
+		})
+
+		return true, err
+	}
+
+	edit := &discordgo.WebhookEdit{}
+
+	if data.Content != "" {
+		edit.Content = &data.Content
+	}
+
+	if len(data.Embeds) > 0 {
+		edit.Embeds = &data.Embeds
+		edit.Components = &data.Components
+	}
+
+	_, err = s.InteractionResponseEdit(m.Interaction, edit)
+
+	return true, err
+}
+
+// lookupMessage shows the profile of the warframe.market user, and a page of their orders
+func lookupMessage(locale string, viewerID string, username string, page int) (*discordgo.InteractionResponseData, error) {
+	translate := func(key string, params *map[string]interface{}) string {
+		return services.LanguageManager.Get(&locale, "commands.discord.lookup."+key, params)
+	}
+
+	profile, err := services.API.GetUser(username)
+
+	if err != nil {
+		return nil, lookupError(locale, username, err)
+	}
+
+	orders, err := services.API.GetUserOrders(profile.IngameName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pages := max((len(orders)+lookupPageSize-1)/lookupPageSize, 1)
+	page = min(max(page, 0), pages-1)
+	shown := orders[min(page*lookupPageSize, len(orders)):min((page+1)*lookupPageSize, len(orders))]
+
+	banned := translate("no", nil)
+
+	if profile.Banned {
+		banned = translate("yes", nil)
+	}
+
+	lines := []string{}
+	options := []discordgo.SelectMenuOption{}
+
+	for _, order := range shown {
+		item := services.Items.Name(order.Item.ID, &locale)
+
+		if item == "" {
+			item = order.Item.En.ItemName
+		}
+
+		if order.ModRank != nil {
+			item = translate("rank", &map[string]interface{}{
+				"Item": item,
+				"Rank": *order.ModRank,
+			})
+		}
+
+		line := translate("order."+order.OrderType, &map[string]interface{}{
+			"Item":     item,
+			"Quantity": order.Quantity,
+			"Price":    services.LanguageManager.FormatPlatinum(&locale, int64(order.Platinum)),
+		})
+
+		lines = append(lines, line)
+		options = append(options, discordgo.SelectMenuOption{
+			Label: truncate(strings.ReplaceAll(line, "**", ""), 100),
+			Value: order.ID,
+		})
+	}
+
+	ordersText := translate("none", nil)
+
+	if len(lines) > 0 {
+		ordersText = truncate(strings.Join(lines, "\n"), embedFieldLimit)
+	}
+
+	var thumbnail *discordgo.MessageEmbedThumbnail = nil
+
+	if profile.Avatar != nil {
+		thumbnail = &discordgo.MessageEmbedThumbnail{
+			URL: "https://warframe.market/static/assets/" + *profile.Avatar,
+		}
+	}
+
+	// The page and name are carried in the custom IDs, so no state is kept between clicks
+	id := func(action string) string {
+		return fmt.Sprintf("lookup_%s_%s_%d_%s", viewerID, action, page, profile.IngameName)
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					CustomID: id("prev"),
+					Label:    translate("buttons.previous", nil),
+					Style:    discordgo.SecondaryButton,
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					CustomID: id("next"),
+					Label:    translate("buttons.next", nil),
+					Style:    discordgo.SecondaryButton,
+					Disabled: page >= pages-1,
+				},
+				discordgo.Button{
+					Label: translate("buttons.open", nil),
+					Style: discordgo.LinkButton,
+					URL:   fmt.Sprintf("https://warframe.market/profile/%s", profile.IngameName),
+				},
+			},
+		},
+	}
+
+	if len(options) > 0 {
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    id("whisper"),
+					Placeholder: translate("whisper_placeholder", nil),
+					Options:     options,
+				},
+			},
+		})
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:     profile.IngameName,
+				URL:       fmt.Sprintf("https://warframe.market/profile/%s", profile.IngameName),
+				Color:     constants.ThemeColor,
+				Thumbnail: thumbnail,
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:   translate("fields.status", nil),
+						Value:  profile.Status,
+						Inline: true,
+					},
+					{
+						Name:   translate("fields.reputation", nil),
+						Value:  strconv.Itoa(profile.Reputation),
+						Inline: true,
+					},
+					{
+						Name:   translate("fields.region", nil),
+						Value:  profile.Region,
+						Inline: true,
+					},
+					{
+						Name:   translate("fields.last_seen", nil),
+						Value:  services.LanguageManager.FormatRelative(&locale, profile.LastSeen),
+						Inline: true,
+					},
+					{
+						Name:   translate("fields.banned", nil),
+						Value:  banned,
+						Inline: true,
+					},
+					{
+						Name: translate("fields.orders", &map[string]interface{}{
+							"Page":  page + 1,
+							"Pages": pages,
+							"Count": len(orders),
+						}),
+						Value: ordersText,
+					},
+				},
+				Footer: Footer(locale),
+			},
+		},
+		Components: components,
+	}, nil
+}
+
+// lookupError explains why the profile couldn't be shown
+func lookupError(locale string, username string, err error) error {
+	if errors.Is(err, services.ErrProfileNotFound) {
+		return errors.New(services.LanguageManager.Get(&locale, "commands.discord.lookup.errors.not_found", &map[string]interface{}{
+			"AccountName": username,
+		}))
+	}
+
+	return err
+}
+
+func LookupPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	return true, "", nil
+}
@@ -0,0 +1,231 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func ProfileCommand() Command {
+	return Command{
+		Name:        "profile",
+		Description: "Show the Vapor Trader profile of a user.",
+		Usage:       "profile user: @VaporTrader",
+		Category:    "Utility",
+		Cooldown:    5 * time.Second,
+		Handler:     ProfileHandler,
+		Permissions: ProfilePermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "user",
+				Description: "The user to show the profile of, yourself if not given.",
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Required:    false,
+			},
+		},
+	}
+}
+
+func ProfileHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	discordUser := interactionUser(m)
+	target := ctx.User
+
+	if option := ctx.Options["user"]; option != nil {
+		discordUser = option.UserValue(s)
+
+		if resolved := m.ApplicationCommandData().Resolved; resolved != nil && resolved.Users[discordUser.ID] != nil {
+			discordUser = resolved.Users[discordUser.ID]
+		}
+
+		user, err := services.DB.GetUserByID(discordUser.ID)
+
+		if err != nil {
+			return false, err
+		}
+
+		if user == nil || user.ID == "" {
+			return false, errors.New(ctx.Translate("commands.discord.profile.errors.unknown_user", nil))
+		}
+
+		target = user
+	}
+
+	// The bot staff can see hidden profiles, to help with reports
+	if target.ID != ctx.User.ID && target.ProfileHidden && !ctx.User.HasPermission("admin") && !ctx.User.HasPermission("moderator") {
+		return false, errors.New(ctx.Translate("commands.discord.profile.errors.hidden", nil))
+	}
+
+	// The achievements are read live from warframe.market, which can be longer than Discord waits for a response
+	err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	embed, err := profileEmbed(ctx, discordUser, target)
+
+	if err != nil {
+		// The interaction was already answered, so the error has to follow up on it
+		_, err = s.FollowupMessageCreate(m.Interaction, true, &discordgo.WebhookParams{
+			Content: ctx.Translate("commands.handler.errors.generic.failed", &map[string]interface{}{
+				"Error": err.Error(),
+			}),
+			Flags: discordgo.MessageFlagsEphemeral,
+		})
+
+		return true, err
+	}
+
+	_, err = s.InteractionResponseEdit(m.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+	})
+
+	return true, err
+}
+
+// profileEmbed combines everything we know about the user into a single embed
+func profileEmbed(ctx CommandContext, discordUser *discordgo.User, user *services.User) (*discordgo.MessageEmbed, error) {
+	none := ctx.Translate("commands.discord.profile.none", nil)
+
+	accounts, err := services.DB.GetLinkedAccounts(user.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	awards, err := services.DB.GetAwardsForUser(user.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	alerts, err := services.CountActivePriceAlertsForUser(user.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   ctx.Translate("commands.discord.profile.fields.tier", nil),
+			Value:  ctx.Translate("commands.discord.profile.tiers."+strings.ReplaceAll(user.Tier(), " ", "_"), nil),
+			Inline: true,
+		},
+		{
+			Name:   ctx.Translate("commands.discord.profile.fields.alerts", nil),
+			Value:  fmt.Sprintf("%d / %d", alerts, user.Limits().PriceAlerts),
+			Inline: true,
+		},
+	}
+
+	if len(accounts) == 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  ctx.Translate("commands.discord.profile.fields.account", nil),
+			Value: none,
+		})
+	}
+
+	for _, account := range accounts {
+		lastSeen := none
+
+		if account.LastSeen.Valid {
+			lastSeen = services.LanguageManager.FormatRelative(&ctx.Locale, account.LastSeen.Time)
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: ctx.Translate("commands.discord.profile.fields.account", nil) + " - " + accountStatus(ctx, &account, platformName(account.Platform)),
+			Value: ctx.Translate("commands.discord.profile.account", &map[string]interface{}{
+				"AccountName": account.Username,
+				"URL":         fmt.Sprintf("https://warframe.market/profile/%s", account.Username),
+				"Reputation":  account.Reputation,
+				"LastSeen":    lastSeen,
+			}),
+			Inline: true,
+		})
+	}
+
+	// Achievements are only shown for the default account, as they are read live from warframe.market
+	if len(accounts) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  ctx.Translate("commands.discord.profile.fields.achievements", nil),
+			Value: truncate(achievementList(accounts[0].Username, none), embedFieldLimit),
+		})
+	}
+
+	badges := none
+
+	if len(awards) > 0 {
+		var lines []string
+
+		for _, award := range awards {
+			lines = append(lines, strings.TrimSpace(award.Badge.Icon+" **"+award.Badge.Name+"**"))
+		}
+
+		badges = truncate(strings.Join(lines, "\n"), embedFieldLimit)
+	}
+
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:  ctx.Translate("commands.discord.profile.fields.badges", nil),
+		Value: badges,
+	})
+
+	return &discordgo.MessageEmbed{
+		Title: ctx.Translate("commands.discord.profile.title", &map[string]interface{}{
+			"UserName": discordUser.Username,
+		}),
+		Color: constants.ThemeColor,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{
+			URL: discordUser.AvatarURL(""),
+		},
+		Fields: fields,
+		Footer: Footer(ctx.Locale),
+	}, nil
+}
+
+// achievementList returns the achievements the warframe.market profile chose to show, one per line
+func achievementList(username string, none string) string {
+	profile, err := services.API.GetUser(username)
+
+	if err != nil {
+		log.Printf("Error fetching the achievements of %s: %s", username, err)
+		return none
+	}
+
+	var lines []string
+
+	for _, achievement := range profile.Achievements {
+		if achievement.Exposed {
+			lines = append(lines, achievement.Name)
+		}
+	}
+
+	if len(lines) == 0 {
+		return none
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// interactionUser returns the Discord user who triggered the interaction, in a guild or not
+func interactionUser(m *discordgo.InteractionCreate) *discordgo.User {
+	if m.User != nil {
+		return m.User
+	}
+
+	return m.Member.User
+}
+
+func ProfilePermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	return true, "", nil
+}
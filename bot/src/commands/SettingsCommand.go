@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// The choice value used to clear a saved language, and fall back to the detected one
+const automaticLanguage = "auto"
+
+func SettingsCommand() Command {
+	return Command{
+		Name:        "settings",
+		Description: "Change your personal settings for the bot.",
+		Usage:       "settings language language: English",
+		Category:    "Utility",
+		Cooldown:    5 * time.Second,
+		Handler:     SettingsHandler,
+		Permissions: SettingsPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "language",
+				Description: "Choose the language the bot responds to you in.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "language",
+						Description: "The language to use, or automatic to follow your Discord client.",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+						Choices:     languageChoices(),
+					},
+				},
+			},
+			{
+				Name:        "privacy",
+				Description: "Choose who can see your profile.",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "profile",
+						Description: "Whether other users can see your profile.",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{
+								Name:              "Public",
+								NameLocalizations: services.LanguageManager.Localizations("commands.discord.settings.privacy.public"),
+								Value:             "public",
+							},
+							{
+								Name:              "Private",
+								NameLocalizations: services.LanguageManager.Localizations("commands.discord.settings.privacy.private"),
+								Value:             "private",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// languageChoices lists every loaded language, as well as the automatic option
+func languageChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := []*discordgo.ApplicationCommandOptionChoice{
+		{
+			Name:              "Automatic",
+			NameLocalizations: services.LanguageManager.Localizations("commands.discord.settings.language.automatic"),
+			Value:             automaticLanguage,
+		},
+	}
+
+	for _, iso := range services.LanguageManager.ISOs() {
+		lang, _ := services.LanguageManager.Language(iso)
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  lang.Name + " (" + iso + ")",
+			Value: iso,
+		})
+	}
+
+	return choices
+}
+
+func SettingsHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	if subcommand := ctx.Options["privacy"]; subcommand != nil {
+		return SettingsPrivacyHandler(s, m, ctx, subcommand)
+	}
+
+	return SettingsLanguageHandler(s, m, ctx)
+}
+
+func SettingsPrivacyHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext, subcommand *discordgo.ApplicationCommandInteractionDataOption) (bool, error) {
+	if len(subcommand.Options) < 1 {
+		return false, errors.New(ctx.Translate("commands.discord.settings.errors.missing", nil))
+	}
+
+	visibility := subcommand.Options[0].StringValue()
+	ctx.User.ProfileHidden = visibility == "private"
+
+	err := services.DB.Save(ctx.User)
+
+	if err != nil {
+		return false, err
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       ctx.Translate("commands.discord.settings.privacy.updated.title", nil),
+					Description: ctx.Translate("commands.discord.settings.privacy.updated."+visibility, nil),
+					Color:       constants.ThemeColor,
+					Footer:      Footer(ctx.Locale),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func SettingsLanguageHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	subcommand := ctx.Options["language"]
+
+	if subcommand == nil || len(subcommand.Options) < 1 {
+		return false, errors.New(ctx.Translate("commands.discord.settings.errors.missing", nil))
+	}
+
+	choice := subcommand.Options[0].StringValue()
+
+	if choice == automaticLanguage {
+		ctx.User.Locale = sql.NullString{Valid: false}
+	} else {
+		if _, ok := services.LanguageManager.Language(choice); !ok {
+			return false, errors.New(ctx.Translate("commands.discord.settings.errors.unknown_language", &map[string]interface{}{
+				"Language": choice,
+			}))
+		}
+
+		ctx.User.Locale = sql.NullString{String: choice, Valid: true}
+	}
+
+	err := services.DB.Save(ctx.User)
+
+	if err != nil {
+		return false, err
+	}
+
+	// Respond in the newly selected language
+	ctx.Locale = ResolveLocale(m, ctx.User)
+	lang, _ := services.LanguageManager.Language(ctx.Locale)
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title: ctx.Translate("commands.discord.settings.language.updated.title", nil),
+					Description: ctx.Translate("commands.discord.settings.language.updated.description", &map[string]interface{}{
+						"Language": lang.Name,
+					}),
+					Color:  constants.ThemeColor,
+					Footer: Footer(ctx.Locale),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	return true, err
+}
+
+func SettingsPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	return true, "", nil
+}
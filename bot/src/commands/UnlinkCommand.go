@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"vaportrader/src/constants"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func UnlinkCommand() Command {
+	return Command{
+		Name:        "unlink",
+		Description: "Unlink a Warframe Market account from your Discord account.",
+		Usage:       "unlink platform: PC",
+		Category:    "Utility",
+		Cooldown:    5 * time.Second,
+		Handler:     UnlinkHandler,
+		Permissions: UnlinkPermissions,
+		Action:      UnlinkAction,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "platform",
+				Description: "The platform of the account to unlink, if you linked more than one.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     platformChoices(),
+				Required:    false,
+			},
+		},
+	}
+}
+
+func UnlinkHandler(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, error) {
+	accounts, err := services.DB.GetLinkedAccounts(ctx.User.ID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if len(accounts) == 0 {
+		return false, errors.New(ctx.Translate("commands.discord.unlink.errors.none", nil))
+	}
+
+	var account *services.LinkedAccount = nil
+
+	if option := ctx.Options["platform"]; option != nil {
+		for i := range accounts {
+			if accounts[i].Platform == option.StringValue() {
+				account = &accounts[i]
+			}
+		}
+
+		if account == nil {
+			return false, errors.New(ctx.Translate("commands.discord.unlink.errors.not_linked", &map[string]interface{}{
+				"Platform": platformName(option.StringValue()),
+			}))
+		}
+	} else if len(accounts) == 1 {
+		account = &accounts[0]
+	} else {
+		return false, errors.New(ctx.Translate("commands.discord.unlink.errors.choose_platform", nil))
+	}
+
+	err = s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title: ctx.Translate("commands.discord.unlink.confirm.title", nil),
+					Description: ctx.Translate("commands.discord.unlink.confirm.description", &map[string]interface{}{
+						"AccountName": account.Username,
+						"Platform":    platformName(account.Platform),
+					}),
+					Color:  constants.ThemeColor,
+					Footer: Footer(ctx.Locale),
+				},
+			},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							CustomID: "unlink_account_wfm_" + ctx.User.ID + "_" + account.Platform + "_confirm",
+							Label:    ctx.Translate("commands.discord.unlink.confirm.yes", nil),
+							Style:    discordgo.DangerButton,
+						},
+						discordgo.Button{
+							CustomID: "unlink_account_wfm_" + ctx.User.ID + "_" + account.Platform + "_cancel",
+							Label:    ctx.Translate("commands.discord.unlink.confirm.no", nil),
+							Style:    discordgo.SecondaryButton,
+						},
+					},
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func UnlinkAction(s *discordgo.Session, m *discordgo.InteractionCreate, ctx ActionContext) (bool, error) {
+	// The custom ID is "unlink_account_wfm_<user>_<platform>_<confirm|cancel>"
+	action := strings.TrimPrefix(ctx.Action.CustomID, "unlink_account_wfm_"+ctx.User.ID+"_")
+	separator := strings.LastIndex(action, "_")
+
+	if action == ctx.Action.CustomID || separator < 0 {
+		return false, errors.New(ctx.Translate("commands.handler.errors.unknown", nil))
+	}
+
+	platform := action[:separator]
+	key := "commands.discord.unlink.cancelled"
+
+	if action[separator+1:] == "confirm" {
+		err := services.DB.UnlinkWFMAccount(ctx.User, platform)
+
+		if errors.Is(err, services.ErrAccountNotLinked) {
+			return false, errors.New(ctx.Translate("commands.discord.unlink.errors.not_linked", &map[string]interface{}{
+				"Platform": platformName(platform),
+			}))
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		key = "commands.discord.unlink.unlinked"
+	}
+
+	err := s.InteractionRespond(m.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title: ctx.Translate(key+".title", nil),
+					Description: ctx.Translate(key+".description", &map[string]interface{}{
+						"Platform": platformName(platform),
+					}),
+					Color:  constants.ThemeColor,
+					Footer: Footer(ctx.Locale),
+				},
+			},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func UnlinkPermissions(s *discordgo.Session, m *discordgo.InteractionCreate, ctx CommandContext) (bool, string, error) {
+	return true, "", nil
+}
@@ -16,3 +16,6 @@ var Footer = &discordgo.MessageEmbedFooter{
 const WFMAuthor = "AltriusRS"
 
 const WFMFooter = BotName + " " + Version + " | Made with ❤️ by " + WFMAuthor
+
+// The maximum number of characters in a single private message on warframe.market
+const WFMMessageLimit = 1000
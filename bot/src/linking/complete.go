@@ -0,0 +1,84 @@
+package linking
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Method is how the user proves they own the warframe.market profile
+type Method string
+
+const (
+	MethodMessage = Method("message") // The code is sent to the bot as a private message
+	MethodProfile = Method("profile") // The token is pasted into the "about" text of the profile
+)
+
+var ErrWrongSender = errors.New("the code was sent from a profile other than the confirmed one")
+
+// ChooseMethod records how the user wants to verify their profile. They may change their mind until the session ends.
+// The interaction of the button they pressed replaces the stored one, as it stays valid for longer.
+func (m *Manager) ChooseMethod(session *services.LinkSession, method Method, interaction *discordgo.Interaction) error {
+	return m.Fire(session, EventChooseMethod, func(s *services.LinkSession) {
+		s.Method = sql.NullString{String: string(method), Valid: true}
+		setInteraction(s, interaction)
+	})
+}
+
+// ProfileToken returns the text the user pastes into their profile's "about" text when verifying that way
+func ProfileToken(session *services.LinkSession) string {
+	return "VaporTrader-" + session.Code.String
+}
+
+// HasProfileToken returns true if the profile's "about" text contains the session's token
+func HasProfileToken(session *services.LinkSession, profile *services.ApiProfile) bool {
+	if !session.Code.Valid {
+		return false
+	}
+
+	token := strings.ToUpper(ProfileToken(session))
+
+	return strings.Contains(strings.ToUpper(profile.AboutRaw), token) || strings.Contains(strings.ToUpper(profile.About), token)
+}
+
+// Complete links the confirmed profile to the Discord user of the session, once wfmID has proven they own it.
+// The link code is used up with the link, the link is announced on the event bus, and the session is verified.
+func (m *Manager) Complete(session *services.LinkSession, linkCode *services.LinkCode, wfmID string) (*services.User, error) {
+	if session.ProfileID.String != wfmID {
+		return nil, ErrWrongSender
+	}
+
+	user, err := services.DB.GetUserByID(session.UserID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The code is used up in the same transaction as the link, so it can't be redeemed twice at the same time,
+	// and isn't lost if the link fails. This also merges the sender's shadow identity (and its alerts) into the Discord user.
+	err = services.DB.RedeemLinkCode(linkCode, user, services.SocketIdentity{
+		ID:       wfmID,
+		Name:     session.ProfileName.String,
+		Locale:   session.ProfileLocale.String,
+		Platform: session.ProfilePlatform.String,
+		LastSeen: session.ProfileLastSeen.Time,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	services.Bus.Publish(services.DomainAccountLinked, user.ID)
+
+	err = m.Verify(session)
+
+	if err != nil {
+		log.Printf("Error completing the link session of %s: %s", session.UserID, err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,308 @@
+package linking
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"vaportrader/src/services"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// How long a user has to finish linking their account, from running /link
+const SessionLifetime = time.Minute * 15
+
+// How long Discord accepts edits to an interaction response, from when the interaction was created
+const InteractionLifetime = time.Minute * 15
+
+var (
+	ErrSessionActive = errors.New("the user is already linking an account")
+	ErrNoSession     = errors.New("the user is not linking an account")
+	ErrNoProfile     = errors.New("no profile has been chosen yet")
+	ErrStaleSession  = errors.New("the link session was changed by someone else")
+)
+
+// Listener is called after every successful transition of a session
+type Listener func(session *services.LinkSession, from State, event Event)
+
+// Manager persists link sessions, and moves them between states
+type Manager struct {
+	mu        sync.RWMutex
+	listeners []Listener
+}
+
+var Sessions = &Manager{}
+
+// OnTransition registers a listener, which is called after every transition of any session
+func (m *Manager) OnTransition(listener Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.listeners = append(m.listeners, listener)
+}
+
+// Start begins a new link session for the Discord user.
+// A session which never got past choosing a profile is replaced, as the user may simply have closed the username prompt.
+// Returns ErrSessionActive if they are further through another one.
+func (m *Manager) Start(userID string) (*services.LinkSession, error) {
+	active, err := m.Active(userID)
+
+	if err == nil {
+		if State(active.State) != StateRequested {
+			return nil, ErrSessionActive
+		}
+
+		err = m.Cancel(active)
+
+		// The session moved on in the meantime, so it is no longer safe to replace
+		if errors.Is(err, ErrStaleSession) {
+			return nil, ErrSessionActive
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, ErrNoSession) {
+		return nil, err
+	}
+
+	session := &services.LinkSession{
+		UserID:    userID,
+		State:     string(StateRequested),
+		ExpiresAt: time.Now().Add(SessionLifetime),
+	}
+
+	err = services.DB.Create(session)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Active returns the unfinished link session of the Discord user.
+// Sessions which ran out of time are expired, and ErrNoSession is returned instead.
+func (m *Manager) Active(userID string) (*services.LinkSession, error) {
+	var session services.LinkSession
+
+	err := services.DB.Inner.
+		Where("user_id = ? AND state NOT IN ?", userID, []string{string(StateVerified), string(StateExpired), string(StateCancelled)}).
+		Order("id DESC").
+		Limit(1).
+		Find(&session).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if session.ID == 0 {
+		return nil, ErrNoSession
+	}
+
+	if session.ExpiresAt.Before(time.Now()) {
+		if err := m.Expire(&session); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrNoSession
+	}
+
+	return &session, nil
+}
+
+// SubmitProfile records the profile the user chose, and the interaction which shows it to them
+func (m *Manager) SubmitProfile(session *services.LinkSession, username string, profile *services.ApiProfile, interaction *discordgo.Interaction) error {
+	return m.Fire(session, EventSubmitProfile, func(s *services.LinkSession) {
+		s.Username = sql.NullString{String: username, Valid: true}
+		s.ProfileID = sql.NullString{String: profile.ID, Valid: true}
+		s.ProfileName = sql.NullString{String: profile.IngameName, Valid: true}
+		s.ProfileLocale = sql.NullString{String: profile.Locale, Valid: profile.Locale != ""}
+		s.ProfilePlatform = sql.NullString{String: profile.Platform, Valid: profile.Platform != ""}
+		s.ProfileLastSeen = sql.NullTime{Time: profile.LastSeen, Valid: !profile.LastSeen.IsZero()}
+		setInteraction(s, interaction)
+	})
+}
+
+// RejectProfile forgets the profile the user chose, so they can enter another username
+func (m *Manager) RejectProfile(session *services.LinkSession) error {
+	return m.Fire(session, EventRejectProfile, func(s *services.LinkSession) {
+		s.ProfileID = sql.NullString{}
+		s.ProfileName = sql.NullString{}
+		s.ProfileLocale = sql.NullString{}
+		s.ProfilePlatform = sql.NullString{}
+		s.ProfileLastSeen = sql.NullTime{}
+	})
+}
+
+// ConfirmProfile accepts the chosen profile, and issues the user a link code for it
+func (m *Manager) ConfirmProfile(session *services.LinkSession) error {
+	if !session.ProfileID.Valid {
+		return ErrNoProfile
+	}
+
+	err := m.Fire(session, EventConfirmProfile, nil)
+
+	if err != nil {
+		return err
+	}
+
+	code, err := services.IssueLinkCode(session.UserID)
+
+	if err != nil {
+		return err
+	}
+
+	return m.Fire(session, EventIssueCode, func(s *services.LinkSession) {
+		s.LinkCodeID = sql.NullInt64{Int64: int64(code.ID), Valid: true}
+		s.Code = sql.NullString{String: code.Code, Valid: true}
+		// The code and the session run out at the same time
+		s.ExpiresAt = code.ExpiresAt
+	})
+}
+
+// Verify completes the session once its code was sent from the confirmed profile
+func (m *Manager) Verify(session *services.LinkSession) error {
+	return m.Fire(session, EventVerify, nil)
+}
+
+// RejectSender records that the session's code was sent from the wrong profile. The session stays open.
+func (m *Manager) RejectSender(session *services.LinkSession) error {
+	return m.Fire(session, EventWrongSender, nil)
+}
+
+// Expire ends the session because it ran out of time
+func (m *Manager) Expire(session *services.LinkSession) error {
+	return m.Fire(session, EventExpire, nil)
+}
+
+// Sweep expires every unfinished session which ran out of time, so their users can be told about it
+func (m *Manager) Sweep() error {
+	var sessions []services.LinkSession
+
+	err := services.DB.Inner.
+		Where("state NOT IN ? AND expires_at < ?", []string{string(StateVerified), string(StateExpired), string(StateCancelled)}, time.Now()).
+		Find(&sessions).Error
+
+	if err != nil {
+		return err
+	}
+
+	for i := range sessions {
+		err = m.Expire(&sessions[i])
+
+		// Someone else finished or expired the session first
+		if errors.Is(err, ErrStaleSession) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Watch sweeps the expired sessions at the given interval, until the process exits
+func (m *Manager) Watch(interval time.Duration) {
+	for {
+		err := m.Sweep()
+
+		if err != nil {
+			log.Printf("Error expiring link sessions: %s", err)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// Cancel ends the session at the user's request
+func (m *Manager) Cancel(session *services.LinkSession) error {
+	return m.Fire(session, EventCancel, nil)
+}
+
+// Fire applies the event to the session, runs the change, and saves the result.
+// The save only succeeds if nobody else moved the session in the meantime.
+func (m *Manager) Fire(session *services.LinkSession, event Event, change func(s *services.LinkSession)) error {
+	from := State(session.State)
+	to, err := Transition(from, event)
+
+	if err != nil {
+		return err
+	}
+
+	updated := *session
+	updated.State = string(to)
+
+	if change != nil {
+		change(&updated)
+	}
+
+	result := services.DB.Inner.Model(&services.LinkSession{}).
+		Where("id = ? AND state = ?", session.ID, string(from)).
+		Select("*").
+		Omit("id", "created_at").
+		Updates(&updated)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrStaleSession
+	}
+
+	*session = updated
+
+	m.mu.RLock()
+	listeners := m.listeners
+	m.mu.RUnlock()
+
+	for _, listener := range listeners {
+		m.notify(listener, session, from, event)
+	}
+
+	return nil
+}
+
+// notify calls a single listener, so that one failing listener doesn't affect the others
+func (m *Manager) notify(listener Listener, session *services.LinkSession, from State, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Link session listener panicked on %s: %v", event, r)
+		}
+	}()
+
+	listener(session, from, event)
+}
+
+// Interaction rebuilds the Discord interaction showing the session, which is enough to edit its response.
+// Returns nil if there is no interaction, or its token has expired.
+func Interaction(session *services.LinkSession) *discordgo.Interaction {
+	if !session.InteractionToken.Valid {
+		return nil
+	}
+
+	// Sessions saved before the creation time was recorded are trusted until the session itself expires
+	if session.InteractionAt.Valid && time.Since(session.InteractionAt.Time) >= InteractionLifetime {
+		return nil
+	}
+
+	return &discordgo.Interaction{
+		AppID: session.InteractionAppID.String,
+		Token: session.InteractionToken.String,
+	}
+}
+
+func setInteraction(session *services.LinkSession, interaction *discordgo.Interaction) {
+	if interaction == nil {
+		return
+	}
+
+	session.InteractionAppID = sql.NullString{String: interaction.AppID, Valid: true}
+	session.InteractionToken = sql.NullString{String: interaction.Token, Valid: true}
+	session.InteractionAt = sql.NullTime{Time: time.Now(), Valid: true}
+}
@@ -0,0 +1,79 @@
+package linking
+
+import "fmt"
+
+// State is a step of the account link flow
+type State string
+
+const (
+	StateRequested        = State("requested")         // The user ran /link, and is choosing their warframe.market profile
+	StateProfileConfirmed = State("profile_confirmed") // The user confirmed the profile is theirs
+	StateCodeIssued       = State("code_issued")       // The user was given a code to send from the profile
+	StateVerified         = State("verified")          // The code was sent from the profile, and the accounts are linked
+	StateExpired          = State("expired")           // The flow was not finished in time
+	StateCancelled        = State("cancelled")         // The user gave up on the flow
+)
+
+// Terminal returns true if no further transitions are possible from the state
+func (s State) Terminal() bool {
+	return s == StateVerified || s == StateExpired || s == StateCancelled
+}
+
+// Event is something which happens during the account link flow, and may move it to another state
+type Event string
+
+const (
+	EventSubmitProfile  = Event("submit_profile")  // The user entered a username, and its profile was found
+	EventRejectProfile  = Event("reject_profile")  // The user said the profile is not theirs
+	EventConfirmProfile = Event("confirm_profile") // The user said the profile is theirs
+	EventIssueCode      = Event("issue_code")      // A link code was generated for the user
+	EventVerify         = Event("verify")          // The code was sent from the confirmed profile
+	EventExpire         = Event("expire")          // The flow ran out of time
+	EventCancel         = Event("cancel")          // The user cancelled the flow
+	EventChooseMethod   = Event("choose_method")   // The user chose how to verify the profile
+	EventWrongSender    = Event("wrong_sender")    // The code was sent from a profile other than the confirmed one
+)
+
+// Transitions lists the state each event leads to, from every state it is allowed in
+var Transitions = map[State]map[Event]State{
+	StateRequested: {
+		EventSubmitProfile:  StateRequested,
+		EventRejectProfile:  StateRequested,
+		EventConfirmProfile: StateProfileConfirmed,
+		EventExpire:         StateExpired,
+		EventCancel:         StateCancelled,
+	},
+	StateProfileConfirmed: {
+		EventIssueCode: StateCodeIssued,
+		EventExpire:    StateExpired,
+		EventCancel:    StateCancelled,
+	},
+	StateCodeIssued: {
+		EventChooseMethod: StateCodeIssued,
+		EventVerify:       StateVerified,
+		EventWrongSender:  StateCodeIssued,
+		EventExpire:       StateExpired,
+		EventCancel:       StateCancelled,
+	},
+}
+
+// TransitionError is returned when an event is not allowed in the current state
+type TransitionError struct {
+	From  State
+	Event Event
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("the event '%s' is not allowed in the state '%s'", e.Event, e.From)
+}
+
+// Transition returns the state the event leads to from the given state
+func Transition(from State, event Event) (State, error) {
+	to, ok := Transitions[from][event]
+
+	if !ok {
+		return from, &TransitionError{From: from, Event: event}
+	}
+
+	return to, nil
+}
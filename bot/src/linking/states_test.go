@@ -0,0 +1,118 @@
+package linking
+
+import (
+	"errors"
+	"testing"
+)
+
+var allStates = []State{StateRequested, StateProfileConfirmed, StateCodeIssued, StateVerified, StateExpired, StateCancelled}
+
+var allEvents = []Event{
+	EventSubmitProfile,
+	EventRejectProfile,
+	EventConfirmProfile,
+	EventIssueCode,
+	EventVerify,
+	EventExpire,
+	EventCancel,
+	EventChooseMethod,
+	EventWrongSender,
+}
+
+func TestTransition(t *testing.T) {
+	// Every allowed transition. Any pair missing from this table must be rejected.
+	allowed := []struct {
+		from  State
+		event Event
+		to    State
+	}{
+		{StateRequested, EventSubmitProfile, StateRequested},
+		{StateRequested, EventRejectProfile, StateRequested},
+		{StateRequested, EventConfirmProfile, StateProfileConfirmed},
+		{StateRequested, EventExpire, StateExpired},
+		{StateRequested, EventCancel, StateCancelled},
+
+		{StateProfileConfirmed, EventIssueCode, StateCodeIssued},
+		{StateProfileConfirmed, EventExpire, StateExpired},
+		{StateProfileConfirmed, EventCancel, StateCancelled},
+
+		{StateCodeIssued, EventChooseMethod, StateCodeIssued},
+		{StateCodeIssued, EventVerify, StateVerified},
+		{StateCodeIssued, EventWrongSender, StateCodeIssued},
+		{StateCodeIssued, EventExpire, StateExpired},
+		{StateCodeIssued, EventCancel, StateCancelled},
+	}
+
+	expected := map[State]map[Event]State{}
+
+	for _, transition := range allowed {
+		if expected[transition.from] == nil {
+			expected[transition.from] = map[Event]State{}
+		}
+
+		expected[transition.from][transition.event] = transition.to
+	}
+
+	for _, from := range allStates {
+		for _, event := range allEvents {
+			to, err := Transition(from, event)
+			want, ok := expected[from][event]
+
+			if ok {
+				if err != nil {
+					t.Errorf("Transition(%s, %s) returned error %s, want %s", from, event, err, want)
+				} else if to != want {
+					t.Errorf("Transition(%s, %s) = %s, want %s", from, event, to, want)
+				}
+
+				continue
+			}
+
+			var transitionErr *TransitionError
+
+			if !errors.As(err, &transitionErr) {
+				t.Errorf("Transition(%s, %s) = %s, %v, want a TransitionError", from, event, to, err)
+				continue
+			}
+
+			if transitionErr.From != from || transitionErr.Event != event {
+				t.Errorf("Transition(%s, %s) returned an error for %s, %s", from, event, transitionErr.From, transitionErr.Event)
+			}
+
+			if to != from {
+				t.Errorf("rejected Transition(%s, %s) moved to %s, want to stay in %s", from, event, to, from)
+			}
+		}
+	}
+}
+
+func TestTransitionsCovered(t *testing.T) {
+	// The table must only use known states and events, so the test above covers all of it
+	known := map[Event]bool{}
+
+	for _, event := range allEvents {
+		known[event] = true
+	}
+
+	for from, events := range Transitions {
+		if from.Terminal() {
+			t.Errorf("the terminal state %s has transitions", from)
+		}
+
+		for event := range events {
+			if !known[event] {
+				t.Errorf("the event %s from %s is not covered by the tests", event, from)
+			}
+		}
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	terminal := map[State]bool{StateVerified: true, StateExpired: true, StateCancelled: true}
+
+	for _, state := range allStates {
+		if state.Terminal() != terminal[state] {
+			t.Errorf("%s.Terminal() = %t, want %t", state, state.Terminal(), terminal[state])
+		}
+	}
+}
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"log"
 	"os"
 	"os/signal"
 	"time"
 	"vaportrader/src/commands"
+	"vaportrader/src/linking"
 	"vaportrader/src/services"
 	"vaportrader/src/socket"
 
@@ -27,18 +27,12 @@ func main() {
 	}
 
 	services.InitDatabase()
+	services.StartAchievements(services.Bus)
 	services.InitSocket(s)
 	services.InitI18n()
 
-	lm, err := json.Marshal(services.LanguageManager)
-
-	print(string(lm))
-
-	println(services.LanguageManager.Get(nil, "constants.wfm.footer", &map[string]interface{}{
-		"maintainer": "Altrius",
-	}))
-
-	os.Exit(0)
+	// Pick up changes to the language files without a restart
+	go services.LanguageManager.Watch(time.Second * 5)
 
 	socket.Load()
 
@@ -47,6 +41,34 @@ func main() {
 		socket.CMDHandler.HandleCommand(services.Socket, message)
 	})
 
+	// Keep the best prices of every item in memory, and match them against the price alerts whenever they change
+	services.Orders.Attach(services.Socket.Events)
+	services.Orders.OnChange(func(itemID string, platform string) {
+		err := services.DB.TriggerAlerts(itemID, platform)
+		if err != nil {
+			log.Printf("Error triggering alerts: %s", err)
+		}
+	})
+
+	// Compare sets against their parts whenever either changes price
+	services.Orders.OnChange(services.Sets.OnBookChange)
+
+	go services.Orders.Maintain(time.Minute)
+
+	// Tell the users waiting for a seller of their item once they go in game
+	services.Presences.OnChange(func(userID string, previous string, current string) {
+		if current != services.PresenceInGame {
+			return
+		}
+
+		err := services.DB.TriggerInGameAlerts(userID)
+		if err != nil {
+			log.Printf("Error triggering in-game alerts: %s", err)
+		}
+	})
+
+	go services.Presences.Watch(time.Minute)
+
 	services.Socket.SetOrderHook(func(order *services.SubscriptionsNewOrder) {
 		log.Printf("New order: %s | %s | %d * %s @ %d platinum", order.User.GameName, order.OrderType, order.Quantity, order.Item.EN.Name, order.Price)
 
@@ -55,6 +77,12 @@ func main() {
 			log.Printf("Error inserting order: %s", err)
 			return
 		}
+
+		// Orders carry the current in-game name, which catches renamed linked accounts
+		err = services.DB.ObserveAccountName(order.User.ID, order.User.GameName)
+		if err != nil {
+			log.Printf("Error updating account name: %s", err)
+		}
 	})
 
 	// Create a new Discord session using the provided bot token.
@@ -92,6 +120,15 @@ func main() {
 
 	commands.Load(s)
 
+	// Expire abandoned link sessions, so their users are told about it
+	go linking.Sessions.Watch(time.Minute)
+
+	// Keep the linked profiles up to date, now that renames can be reported on Discord
+	go services.RunProfileRefresh()
+
+	// Walk through every set, now that new flips can be posted on Discord
+	go services.Sets.Run()
+
 	// Add command handlers
 	s.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		commands.CMDHandler.HandleCommand(s, i)
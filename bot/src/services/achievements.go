@@ -0,0 +1,133 @@
+package services
+
+import (
+	"log"
+	"strings"
+)
+
+// AchievementRule awards a badge once the user has earned it
+type AchievementRule struct {
+	BadgeID int32
+	Events  []DomainEventKind                                       // The events after which the rule is checked
+	Earned  func(user *User, counts map[DomainEventKind]int64) bool // counts holds the new total of the event which was counted
+}
+
+// The events which are counted per user, for rules such as "10 alerts triggered"
+var countedEvents = map[DomainEventKind]bool{
+	DomainAlertTriggered: true,
+	DomainPriceChecked:   true,
+}
+
+// Every event a user causes gives premium users a chance to receive their badge
+var allEvents = []DomainEventKind{DomainAccountLinked, DomainAlertTriggered, DomainPriceChecked}
+
+// The entitlements which earn the supporter badge
+var supporterEntitlements = []string{"premium1", "premium2", "premium3"}
+
+var AchievementRules = []AchievementRule{
+	{
+		BadgeID: BadgeLinkedAccount,
+		Events:  []DomainEventKind{DomainAccountLinked},
+		Earned:  func(user *User, counts map[DomainEventKind]int64) bool { return true },
+	},
+	countRule(BadgeAlertRookie, DomainAlertTriggered, 10),
+	countRule(BadgeAlertVeteran, DomainAlertTriggered, 100),
+	countRule(BadgePriceChecker, DomainPriceChecked, 25),
+	countRule(BadgeMarketAnalyst, DomainPriceChecked, 250),
+	{
+		BadgeID: BadgeSupporter,
+		Events:  allEvents,
+		Earned: func(user *User, counts map[DomainEventKind]int64) bool {
+			for _, entitlement := range supporterEntitlements {
+				if user.HasPermission(entitlement) {
+					return true
+				}
+			}
+
+			return false
+		},
+	},
+}
+
+// countRule is earned once the user has caused the event at least threshold times
+func countRule(badgeID int32, kind DomainEventKind, threshold int64) AchievementRule {
+	return AchievementRule{
+		BadgeID: badgeID,
+		Events:  []DomainEventKind{kind},
+		Earned: func(user *User, counts map[DomainEventKind]int64) bool {
+			return counts[kind] >= threshold
+		},
+	}
+}
+
+// StartAchievements checks the achievement rules after every domain event
+func StartAchievements(bus *EventBus) {
+	for _, kind := range allEvents {
+		bus.Subscribe(kind, evaluateAchievements)
+	}
+}
+
+// evaluateAchievements counts the event, then awards every badge the user has now earned
+func evaluateAchievements(event DomainEvent) {
+	counts := map[DomainEventKind]int64{}
+
+	if countedEvents[event.Kind] {
+		count, err := DB.IncrementCounter(event.UserID, string(event.Kind))
+
+		if err != nil {
+			log.Printf("Error counting %s for %s: %s", event.Kind, event.UserID, err)
+			return
+		}
+
+		counts[event.Kind] = count
+	}
+
+	// Shadow identities keep counting, as their counters are added to the user's when they link,
+	// but badges are only awarded to Discord users who can be told about them
+	if strings.HasPrefix(event.UserID, ShadowUserPrefix) {
+		return
+	}
+
+	user, err := DB.GetUserByID(event.UserID)
+
+	if err != nil || user == nil || user.ID == "" {
+		log.Printf("Error loading %s to check their achievements: %v", event.UserID, err)
+		return
+	}
+
+	for _, rule := range AchievementRules {
+		if !ruleListensTo(rule, event.Kind) || !rule.Earned(user, counts) {
+			continue
+		}
+
+		_, err = DB.GrantAward(user.ID, rule.BadgeID)
+
+		if err != nil {
+			log.Printf("Error awarding badge %d to %s: %s", rule.BadgeID, user.ID, err)
+		}
+	}
+}
+
+func ruleListensTo(rule AchievementRule, kind DomainEventKind) bool {
+	for _, listened := range rule.Events {
+		if listened == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backfillSupporterBadges awards the supporter badge to every premium user who doesn't have it yet, such as those who
+// subscribed before the badge existed. They aren't notified, as nothing new happened to them.
+func (db *Database) backfillSupporterBadges() error {
+	var mask uint32
+
+	for _, entitlement := range supporterEntitlements {
+		mask |= Entitlements[entitlement]
+	}
+
+	return db.Inner.Exec(`INSERT INTO awards (created_at, updated_at, user_id, badge_id)
+		SELECT NOW(), NOW(), id, ? FROM users WHERE entitlements & ? != 0 AND id NOT LIKE ?
+		ON CONFLICT (user_id, badge_id) DO NOTHING`, BadgeSupporter, mask, ShadowUserPrefix+"%").Error
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The price mode of alerts waiting for a seller of their item to go in game, rather than for a price
+const AlertModeInGame = "ingame"
+
+// How long an alert stays quiet after matching an order, so a busy item doesn't flood its owner
+const AlertCooldown = time.Minute * 10
+
+// Called after a price alert matches the best order of its item
+var alertTriggeredHook func(alert *Alert, order *BookOrder)
+
+// SetAlertTriggeredHook sets the function called after a price alert matches the best order of its item
+func SetAlertTriggeredHook(hook func(alert *Alert, order *BookOrder)) {
+	alertTriggeredHook = hook
+}
+
+// TriggerAlerts matches the best orders of the item in the order book against every active price alert for it.
+// The cheapest sell order triggers alerts waiting for a price at or below it, and the best buy order alerts waiting for one at or above.
+// Alerts for a rank are only matched against the best orders of that rank.
+func (db *Database) TriggerAlerts(itemID string, platform string) error {
+	var ranks []sql.NullInt32
+
+	err := db.Inner.Model(&Alert{}).
+		Where("item_id = ? AND platform = ? AND active AND price_mode <> ?", itemID, platform, AlertModeInGame).
+		Distinct().
+		Pluck("mod_rank", &ranks).Error
+
+	if err != nil {
+		return err
+	}
+
+	for _, rank := range ranks {
+		for _, order := range []*BookOrder{Orders.BestAsk(itemID, platform, alertRank(rank)), Orders.BestBid(itemID, platform, alertRank(rank))} {
+			if order == nil {
+				continue
+			}
+
+			err = db.triggerAlerts(order, rank)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// triggerAlerts matches a single order against every active price alert for its item and the rank
+func (db *Database) triggerAlerts(order *BookOrder, rank sql.NullInt32) error {
+	query := db.alertsFor(order).Where("price_mode <> ?", AlertModeInGame)
+
+	if rank.Valid {
+		query = query.Where("mod_rank = ?", rank.Int32)
+	} else {
+		query = query.Where("mod_rank IS NULL")
+	}
+
+	switch OrderType(order.OrderType) {
+	case OrderTypeSell:
+		query = query.Where("upper_price >= ?", order.Price)
+	case OrderTypeBuy:
+		query = query.Where("lower_price <= ?", order.Price)
+	default:
+		return nil
+	}
+
+	return db.fireAlerts(query, order)
+}
+
+// TriggerInGameAlerts matches the sell orders of the user who just went in game against the alerts waiting for them.
+// An alert with a price only matches sellers asking at most that much, otherwise any seller of its item matches.
+func (db *Database) TriggerInGameAlerts(userID string) error {
+	// Only the cheapest order of each item and rank matters, as one whisper covers them all
+	type rankedKey struct {
+		bookKey
+		rank int
+	}
+
+	cheapest := map[rankedKey]BookOrder{}
+
+	for _, order := range Orders.BySeller(userID) {
+		key := rankedKey{bookKey{itemID: order.ItemID, platform: order.Platform}, bookRank(order.Rank)}
+
+		if !isClean(order) {
+			continue
+		}
+
+		if existing, ok := cheapest[key]; !ok || order.Price < existing.Price {
+			cheapest[key] = order
+		}
+	}
+
+	for _, order := range cheapest {
+		query := db.alertsFor(&order).
+			Where("price_mode = ?", AlertModeInGame).
+			Where("upper_price = 0 OR upper_price >= ?", order.Price).
+			Where("mod_rank IS NULL OR mod_rank = ?", bookRank(order.Rank))
+
+		err := db.fireAlerts(query, &order)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alertsFor returns a query for the active alerts of the order's item which aren't cooling down
+func (db *Database) alertsFor(order *BookOrder) *gorm.DB {
+	return db.Inner.
+		Where("item_id = ? AND platform = ? AND order_type = ? AND active", order.ItemID, order.Platform, order.OrderType).
+		Where("triggered_at IS NULL OR triggered_at < ?", time.Now().Add(-AlertCooldown)).
+		// Nobody wants to be alerted about their own orders
+		Where("user_id NOT IN (?)", db.Inner.Model(&LinkedAccount{}).Select("user_id").Where("wfm_id = ?", order.UserID))
+}
+
+// fireAlerts records a hit for every alert found by the query, and tells their owners about the order
+func (db *Database) fireAlerts(query *gorm.DB, order *BookOrder) error {
+	now := time.Now()
+
+	var alerts []*Alert
+
+	err := query.Find(&alerts).Error
+
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		err = db.Inner.Model(alert).Updates(map[string]interface{}{
+			"hits":         gorm.Expr("hits + 1"),
+			"triggered_at": now,
+		}).Error
+
+		if err != nil {
+			log.Printf("Error recording the hit of alert %d: %s", alert.ID, err)
+			continue
+		}
+
+		Bus.Publish(DomainAlertTriggered, alert.UserId)
+
+		if alertTriggeredHook != nil {
+			go alertTriggeredHook(alert, order)
+		}
+	}
+
+	return nil
+}
+
+// alertRank returns the rank of the orders an alert watches, or nil for any rank
+func alertRank(rank sql.NullInt32) *int {
+	if !rank.Valid {
+		return nil
+	}
+
+	value := int(rank.Int32)
+	return &value
+}
+
+// isClean returns true if the order isn't an outlier among the other orders of its item
+func isClean(order BookOrder) bool {
+	orders, _ := Orders.Clean(order.ItemID, order.Platform, order.Rank, order.OrderType)
+
+	for _, clean := range orders {
+		if clean.ID == order.ID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,14 +3,24 @@ package services
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 )
 
+// warframe.market allows about three requests per second
+const APIRequestInterval = time.Second / 3
+
+var ErrProfileNotFound = errors.New("no warframe.market profile has this name")
+
 type APIClient struct {
 	client *http.Client
+	mu     sync.Mutex
+	next   time.Time // The earliest time the next request may be sent
 }
 
 func NewAPIClient() *APIClient {
@@ -21,8 +31,29 @@ func NewAPIClient() *APIClient {
 	}
 }
 
+// wait blocks until the next request may be sent without going over the rate limit
+func (a *APIClient) wait() {
+	a.mu.Lock()
+	at := a.next
+
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+
+	a.next = at.Add(APIRequestInterval)
+	a.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+// get requests the url once the rate limit allows it
+func (a *APIClient) get(url string) (*http.Response, error) {
+	a.wait()
+	return a.client.Get(url)
+}
+
 func (a *APIClient) GetItems() ([]ApiItemPartial, error) {
-	response, err := a.client.Get("https://api.warframe.market/v1/items")
+	response, err := a.get("https://api.warframe.market/v1/items")
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +68,7 @@ func (a *APIClient) GetItems() ([]ApiItemPartial, error) {
 }
 
 func (a *APIClient) GetItem(slug string) (*ApiItemGroup, error) {
-	response, err := a.client.Get("https://api.warframe.market/v1/items/" + slug)
+	response, err := a.get("https://api.warframe.market/v1/items/" + slug)
 	if err != nil {
 		return nil, err
 	}
@@ -52,11 +83,17 @@ func (a *APIClient) GetItem(slug string) (*ApiItemGroup, error) {
 }
 
 func (a *APIClient) GetUser(username string) (*ApiProfile, error) {
-	response, err := a.client.Get("https://api.warframe.market/v1/profile/" + username)
+	response, err := a.get("https://api.warframe.market/v1/profile/" + url.PathEscape(username))
 	if err != nil {
 		return nil, err
 	}
 
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrProfileNotFound
+	}
+
 	payload := ApiCoreResponse[ApiProfilePayload]{}
 	err = a.ReadJSON(response.Body, &payload)
 	if err != nil {
@@ -66,6 +103,84 @@ func (a *APIClient) GetUser(username string) (*ApiProfile, error) {
 	return &payload.Payload.Profile, nil
 }
 
+// GetUserOrders returns the visible orders of the warframe.market user, their sell orders first
+func (a *APIClient) GetUserOrders(username string) ([]ApiProfileOrder, error) {
+	response, err := a.get("https://api.warframe.market/v1/profile/" + url.PathEscape(username) + "/orders")
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrProfileNotFound
+	}
+
+	payload := ApiCoreResponse[ApiProfileOrdersPayload]{}
+	err = a.ReadJSON(response.Body, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []ApiProfileOrder
+
+	for _, order := range append(payload.Payload.SellOrders, payload.Payload.BuyOrders...) {
+		if order.Visible {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders, nil
+}
+
+// GetItemOrders returns every order for the item on the given platform
+func (a *APIClient) GetItemOrders(slug string, platform string) ([]ApiOrder, error) {
+	payload := ApiCoreResponse[ApiOrdersPayload]{}
+
+	err := a.getWithPlatform("https://api.warframe.market/v1/items/"+slug+"/orders", platform, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload.Payload.Orders, nil
+}
+
+// GetItemStatistics returns the price history of the item on the given platform
+func (a *APIClient) GetItemStatistics(slug string, platform string) (*ApiStatisticsWindow, error) {
+	payload := ApiCoreResponse[ApiStatisticsPayload]{}
+
+	err := a.getWithPlatform("https://api.warframe.market/v1/items/"+slug+"/statistics", platform, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.Payload.Closed, nil
+}
+
+// getWithPlatform requests the url for the given platform, and decodes the JSON response into v
+func (a *APIClient) getWithPlatform(url string, platform string, v interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Platform", platform)
+
+	a.wait()
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from %s: %s", url, response.Status)
+	}
+
+	return a.ReadJSON(response.Body, v)
+}
+
 func (a *APIClient) ReadBody(r io.Reader) ([]byte, error) {
 	body, err := io.ReadAll(r)
 	if err != nil {
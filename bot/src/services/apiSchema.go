@@ -98,3 +98,76 @@ type ApiProfile struct {
 type ApiProfilePayload struct {
 	Profile ApiProfile `json:"profile"`
 }
+
+type ApiOrderUser struct {
+	ID         string    `json:"id"`
+	IngameName string    `json:"ingame_name"`
+	Status     string    `json:"status"` // Can be "online", "ingame", or "offline"
+	Reputation int       `json:"reputation"`
+	Region     string    `json:"region"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+type ApiOrder struct {
+	ID           string       `json:"id"`
+	Platinum     int          `json:"platinum"`
+	Quantity     int          `json:"quantity"`
+	OrderType    string       `json:"order_type"` // Can be "sell", or "buy"
+	Platform     string       `json:"platform"`
+	Region       string       `json:"region"`
+	Visible      bool         `json:"visible"`
+	ModRank      *int         `json:"mod_rank"` // Only set for items which can be ranked
+	CreationDate time.Time    `json:"creation_date"`
+	LastUpdate   time.Time    `json:"last_update"`
+	User         ApiOrderUser `json:"user"`
+}
+
+// ApiOrderItem is the item of an order listed on a profile
+type ApiOrderItem struct {
+	ID   string             `json:"id"`
+	Slug string             `json:"url_name"`
+	En   ApiItemTranslation `json:"en"`
+}
+
+// ApiProfileOrder is an order listed on a profile, which carries its item instead of its user
+type ApiProfileOrder struct {
+	ID           string       `json:"id"`
+	Platinum     int          `json:"platinum"`
+	Quantity     int          `json:"quantity"`
+	OrderType    string       `json:"order_type"` // Can be "sell", or "buy"
+	Platform     string       `json:"platform"`
+	Region       string       `json:"region"`
+	Visible      bool         `json:"visible"`
+	ModRank      *int         `json:"mod_rank"` // Only set for items which can be ranked
+	CreationDate time.Time    `json:"creation_date"`
+	LastUpdate   time.Time    `json:"last_update"`
+	Item         ApiOrderItem `json:"item"`
+}
+
+type ApiProfileOrdersPayload struct {
+	SellOrders []ApiProfileOrder `json:"sell_orders"`
+	BuyOrders  []ApiProfileOrder `json:"buy_orders"`
+}
+
+type ApiOrdersPayload struct {
+	Orders []ApiOrder `json:"orders"`
+}
+
+type ApiStatistic struct {
+	Datetime time.Time `json:"datetime"`
+	Volume   int       `json:"volume"`
+	Min      float64   `json:"min_price"`
+	Max      float64   `json:"max_price"`
+	Average  float64   `json:"avg_price"`
+	Median   float64   `json:"median"`
+	ModRank  *int      `json:"mod_rank"` // Only set for items which can be ranked
+}
+
+type ApiStatisticsWindow struct {
+	FortyEightHours []ApiStatistic `json:"48hours"`
+	NinetyDays      []ApiStatistic `json:"90days"`
+}
+
+type ApiStatisticsPayload struct {
+	Closed ApiStatisticsWindow `json:"statistics_closed"`
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The badges awarded automatically, see BadgeCatalog
+const (
+	BadgeLinkedAccount = 4
+	BadgeAlertRookie   = 5
+	BadgeAlertVeteran  = 6
+	BadgePriceChecker  = 7
+	BadgeMarketAnalyst = 8
+	BadgeSupporter     = 9
+)
+
+// Called after a badge is newly awarded to a user
+var badgeAwardedHook func(userID string, badge *Badge)
+
+// SetBadgeAwardedHook sets the function called after a badge is newly awarded to a user
+func SetBadgeAwardedHook(hook func(userID string, badge *Badge)) {
+	badgeAwardedHook = hook
+}
+
+// GrantAward gives the badge to the user, unless they already have it.
+// Returns true if the award was new, in which case the badge awarded hook is called.
+// Achievements are evaluated concurrently, so the unique index decides which of several grants wins.
+func (db *Database) GrantAward(userID string, badgeID int32) (bool, error) {
+	award := Award{
+		UserId:  userID,
+		BadgeId: badgeID,
+	}
+
+	result := db.Inner.Clauses(clause.OnConflict{DoNothing: true}).Create(&award)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected != 1 {
+		return false, nil
+	}
+
+	log.Printf("Awarded badge %d to %s", badgeID, userID)
+
+	if badge := GetBadge(badgeID); badge != nil && badgeAwardedHook != nil {
+		go badgeAwardedHook(userID, badge)
+	}
+
+	return true, nil
+}
+
+// dedupeAwards removes every award of a badge to a user but the first, which were possible before awards were unique
+func dedupeAwards(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Award{}) {
+		return nil
+	}
+
+	return db.Exec(`DELETE FROM awards a USING awards b
+		WHERE a.user_id = b.user_id AND a.badge_id = b.badge_id AND a.id > b.id`).Error
+}
+
+// RevokeAward takes the badge away from the user. Returns false if they didn't have it.
+func (db *Database) RevokeAward(userID string, badgeID int32) (bool, error) {
+	result := db.Inner.Unscoped().Where("user_id = ? AND badge_id = ?", userID, badgeID).Delete(&Award{})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// GetAwardsForUser returns every badge awarded to the user, the oldest first
+func (db *Database) GetAwardsForUser(userID string) ([]Award, error) {
+	var awards []Award
+
+	err := db.Inner.Preload("Badge").Where("user_id = ?", userID).Order("created_at").Find(&awards).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return awards, nil
+}
+
+// IncrementCounter adds one to the named counter of the user, and returns its new value
+func (db *Database) IncrementCounter(userID string, name string) (int64, error) {
+	counter := UserCounter{UserID: userID, Name: name, Count: 1}
+
+	err := db.Inner.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": clause.Expr{SQL: "user_counters.count + 1"}}),
+	}, clause.Returning{Columns: []clause.Column{{Name: "count"}}}).Create(&counter).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Count, nil
+}
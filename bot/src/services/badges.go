@@ -0,0 +1,45 @@
+package services
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// The badges which can be awarded, kept in sync with the database on start up
+var BadgeCatalog = []Badge{
+	{ID: 1, Name: "Developer", Description: "Helped build Vapor Trader.", Icon: "🛠️", Achievable: false},
+	{ID: 2, Name: "Staff", Description: "Keeps the Vapor Trader community running.", Icon: "🛡️", Achievable: false},
+	{ID: 3, Name: "Early Adopter", Description: "Used Vapor Trader during its early days.", Icon: "🌱", Achievable: false},
+	{ID: BadgeLinkedAccount, Name: "Linked", Description: "Linked a Warframe Market account.", Icon: "🔗", Achievable: true},
+	{ID: BadgeAlertRookie, Name: "On Alert", Description: "Had 10 price alerts triggered.", Icon: "🔔", Achievable: true},
+	{ID: BadgeAlertVeteran, Name: "Watchtower", Description: "Had 100 price alerts triggered.", Icon: "📡", Achievable: true},
+	{ID: BadgePriceChecker, Name: "Price Checker", Description: "Checked 25 prices.", Icon: "🔍", Achievable: true},
+	{ID: BadgeMarketAnalyst, Name: "Market Analyst", Description: "Checked 250 prices.", Icon: "📈", Achievable: true},
+	{ID: BadgeSupporter, Name: "Supporter", Description: "Supports Vapor Trader with a premium subscription.", Icon: "💎", Achievable: true},
+}
+
+// seedBadges creates or updates every badge in the catalog
+func (db *Database) seedBadges() error {
+	for _, badge := range BadgeCatalog {
+		err := db.Inner.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "description", "icon", "achievable", "updated_at"}),
+		}).Create(&badge).Error
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetBadge returns the badge from the catalog, or nil if it doesn't exist
+func GetBadge(id int32) *Badge {
+	for i := range BadgeCatalog {
+		if int32(BadgeCatalog[i].ID) == id {
+			return &BadgeCatalog[i]
+		}
+	}
+
+	return nil
+}
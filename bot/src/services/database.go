@@ -1,6 +1,7 @@
 package services
 
 import (
+	"database/sql"
 	"log"
 	"os"
 	"strings"
@@ -19,6 +20,8 @@ func NewDatabase() *Database {
 
 	db, err := gorm.Open(postgres.Open(os.Getenv("DB_STRING")), &gorm.Config{
 		PrepareStmt: true,
+		// Unique violations are returned as gorm.ErrDuplicatedKey, so callers can tell them apart
+		TranslateError: true,
 	})
 
 	if err != nil {
@@ -35,9 +38,49 @@ func NewDatabase() *Database {
 	sqldb.SetMaxIdleConns(10)
 	sqldb.SetConnMaxLifetime(time.Hour)
 
-	db.AutoMigrate(&User{}, &Badge{}, &Award{}, &Alert{}, &Trade{}, &Item{}, &ItemTranslation{}, &StateInfo{}, &TradeInfo{})
+	// Awards must be unique before their index can be created
+	err = dedupeAwards(db)
 
-	return &Database{Inner: db}
+	if err != nil {
+		log.Fatalf("Error removing duplicate awards: %s", err)
+	}
+
+	// Linked accounts must be unique before their index can be created
+	err = dedupeLinkedAccounts(db)
+
+	if err != nil {
+		log.Fatalf("Error removing duplicate linked accounts: %s", err)
+	}
+
+	db.AutoMigrate(&User{}, &Badge{}, &Award{}, &Alert{}, &Trade{}, &Item{}, &ItemTranslation{}, &StateInfo{}, &TradeInfo{}, &OutboxMessage{}, &LinkCode{}, &LinkLockout{}, &LinkSession{}, &LinkedAccount{}, &ReputationRecord{}, &UserCounter{}, &GuildSubscription{})
+
+	database := &Database{Inner: db}
+
+	err = database.migrateLinkedAccounts()
+
+	if err != nil {
+		log.Fatalf("Error migrating linked accounts: %s", err)
+	}
+
+	err = database.migrateWfmLocales()
+
+	if err != nil {
+		log.Fatalf("Error migrating warframe.market locales: %s", err)
+	}
+
+	err = database.seedBadges()
+
+	if err != nil {
+		log.Fatalf("Error seeding badges: %s", err)
+	}
+
+	err = database.backfillSupporterBadges()
+
+	if err != nil {
+		log.Printf("Error backfilling supporter badges: %s", err)
+	}
+
+	return database
 }
 
 func (db *Database) GetUserByID(id string) (*User, error) {
@@ -54,10 +97,15 @@ func (db *Database) GetUserByID(id string) (*User, error) {
 	return &user, nil
 }
 
+// GetUserByWFMID returns the user who linked the warframe.market account, or whose shadow identity it is
 func (db *Database) GetUserByWFMID(wfmid string) (*User, error) {
 	var user User
 
-	err := db.Inner.Find(&user, "wfm_id = ?", wfmid).Limit(1).Error
+	err := db.Inner.
+		Where("id IN (?)", db.Inner.Model(&LinkedAccount{}).Select("user_id").Where("wfm_id = ?", wfmid)).
+		Or("wfm_id = ?", wfmid).
+		Limit(1).
+		Find(&user).Error
 
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
@@ -111,12 +159,23 @@ func (db *Database) InsertOrder(order *SubscriptionsNewOrder) error {
 		kind = 1
 	}
 
+	outlier, err := db.ClassifyOrder(order)
+
+	if err != nil {
+		return err
+	}
+
 	trade := Trade{
-		ID:     order.ID,
-		UserId: order.User.ID,
-		ItemId: order.Item.ID,
-		Kind:   uint8(kind),
-		Price:  uint32(order.Price),
+		ID:      order.ID,
+		UserId:  order.User.ID,
+		ItemId:  order.Item.ID,
+		Kind:    uint8(kind),
+		Price:   uint32(order.Price),
+		Outlier: string(outlier),
+	}
+
+	if rank := orderRank(order); rank != nil {
+		trade.ModRank = sql.NullInt32{Int32: int32(*rank), Valid: true}
 	}
 
 	return db.Inner.Create(&trade).Error
@@ -201,12 +260,42 @@ func (u *User) RevokePermission(entitlement string) {
 	u.Entitlements &= ^mask
 }
 
+// Limits returns the most generous limits out of every entitlement the user has
+func (u *User) Limits() EntitlementLimits {
+	limits := EntitlementLimitTable["none"]
+
+	for entitlement, granted := range EntitlementLimitTable {
+		if !u.HasPermission(entitlement) {
+			continue
+		}
+
+		limits.ItemsPerSearch = max(limits.ItemsPerSearch, granted.ItemsPerSearch)
+		limits.PriceAlerts = max(limits.PriceAlerts, granted.PriceAlerts)
+		limits.PreEntitlement = max(limits.PreEntitlement, granted.PreEntitlement)
+	}
+
+	return limits
+}
+
+// Tier returns the most important entitlement the user has, or "none"
+func (u *User) Tier() string {
+	for _, entitlement := range EntitlementTiers {
+		if u.HasPermission(entitlement) {
+			return entitlement
+		}
+	}
+
+	return "none"
+}
+
 func (i *Trade) AfterSave(tx *gorm.DB) (err error) {
 	tx.Create(&TradeInfo{
 		Time:        i.CreatedAt,
 		ItemId:      i.ItemId,
 		Price:       i.Price,
 		IsSellOrder: i.Kind == 1,
+		ModRank:     i.ModRank,
+		Outlier:     i.Outlier != "",
 	})
 
 	return nil
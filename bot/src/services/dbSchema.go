@@ -10,29 +10,54 @@ import (
 
 type UserEntitlements map[string]uint32
 
+// See entitlements.md for the meaning of each bit
 var Entitlements = UserEntitlements{
-	"none":      0 << 0, // Enable the 1st bit
-	"admin":     0 << 1, // Enable the 2nd bit
-	"moderator": 0 << 2, // Enable the 3rd bit
-	"developer": 0 << 3, // Enable the 4th bit
-	"premium1":  0 << 4, // Enable the 5th bit
-	"premium2":  0 << 5, // Enable the 6th bit
-	"premium3":  0 << 6, // Enable the bit
-	"wfm staff": 0 << 7, // Enable the bit
+	"none":      0,      // No bits enabled
+	"admin":     1 << 0, // Enable the 1st bit
+	"moderator": 1 << 1, // Enable the 2nd bit
+	"developer": 1 << 2, // Enable the 3rd bit
+	"premium1":  1 << 3, // Enable the 4th bit
+	"premium2":  1 << 4, // Enable the 5th bit
+	"premium3":  1 << 5, // Enable the 6th bit
+	"wfm staff": 1 << 6, // Enable the 7th bit
 }
 
+// EntitlementLimits are the limits granted by an entitlement
+type EntitlementLimits struct {
+	ItemsPerSearch int           // How many items are shown in a search
+	PriceAlerts    int           // How many price alerts may be active at once
+	PreEntitlement time.Duration // How long before everyone else alerts are delivered
+}
+
+// See entitlements.md for the limits of each entitlement
+var EntitlementLimitTable = map[string]EntitlementLimits{
+	"none":      {ItemsPerSearch: 3, PriceAlerts: 2},
+	"admin":     {ItemsPerSearch: 6, PriceAlerts: 5},
+	"moderator": {ItemsPerSearch: 6, PriceAlerts: 5},
+	"developer": {ItemsPerSearch: 3, PriceAlerts: 5},
+	"premium1":  {ItemsPerSearch: 6, PriceAlerts: 5, PreEntitlement: 5 * time.Second},
+	"premium2":  {ItemsPerSearch: 8, PriceAlerts: 8, PreEntitlement: 10 * time.Second},
+	"premium3":  {ItemsPerSearch: 10, PriceAlerts: 10, PreEntitlement: 30 * time.Second},
+	"wfm staff": {ItemsPerSearch: 10, PriceAlerts: 10, PreEntitlement: 60 * time.Second},
+}
+
+// The entitlements shown as a user's tier, the most important first
+var EntitlementTiers = []string{"wfm staff", "admin", "moderator", "developer", "premium3", "premium2", "premium1"}
+
 // A struct to represent a user's VaporTrader account
 type User struct {
 	gorm.Model
 	ID                string `gorm:"primaryKey unique"`
 	Name              string
 	Entitlements      uint32
-	Locale            sql.NullString
+	Locale            sql.NullString // The language the user chose, or null to follow their Discord client
+	WfmLocale         sql.NullString // The locale of their warframe.market profile, used on the socket when they haven't chosen one
 	WfmID             sql.NullString `gorm:"unique column:wfm_id"`
 	WfmUsername       sql.NullString `gorm:"unique column:wfm_username"`
 	PreferredPlatform sql.NullString
 	FirstSeen         time.Time `gorm:"autoCreateTime"` // the first time this user was seen (either on the socket, or the bot)
 	LastSeen          time.Time // the last time this user was seen (either on the socket, or the bot)
+	ProfileHidden     bool      // Hides the user's profile from everyone but themselves and the bot staff
 	Awards            []Award   `gorm:"foreignkey:UserId"`
 	Alerts            []Alert   `gorm:"foreignkey:UserId"`
 }
@@ -49,39 +74,50 @@ type Badge struct {
 type Award struct {
 	gorm.Model
 	ID      uint32 `gorm:"'type:Int4' primaryKey unique autoIncrement"` // The unique ID of this award
-	UserId  string `gorm:"index"`
+	UserId  string `gorm:"uniqueIndex:idx_award_user_badge"`            // A user holds each badge at most once
 	User    User   `gorm:"references:ID"`
-	BadgeId int32  `gorm:"index"`
+	BadgeId int32  `gorm:"uniqueIndex:idx_award_user_badge"`
 	Badge   Badge  `gorm:"references:ID"`
 }
 
+// A struct to represent how many times a user did something, such as checking a price
+type UserCounter struct {
+	UserID string `gorm:"primaryKey"`
+	Name   string `gorm:"primaryKey"` // See DomainEventKind
+	Count  int64
+}
+
 // A struct to represent a user's VaporTrader alerts
 type Alert struct {
 	gorm.Model
-	ID            uint32 `gorm:"'type:Int4' primaryKey unique autoIncrement"` // The unique ID of this alert
-	UserId        string `gorm:"index"`
-	User          User   `gorm:"references:ID"`
-	ItemId        string `gorm:"index"`
-	Item          Item   `gorm:"references:ID"`
-	OrderType     string // The order type of this alert ()
-	PriceMode     string // The price mode of this alert ()
-	LowerPrice    uint32 // The minimum price of this alert (inclusive)
-	UpperPrice    uint32 // The maximum price of this alert (inclusive)
-	PriceVariance uint32 // The price variance to trigger the alert
-	Platform      string // The platform this alert applies to
-	Hits          int32  `gorm:"'type:Int4' 'default:0'"`
-	Active        bool   `gorm:"default:true"`
+	ID            uint32        `gorm:"'type:Int4' primaryKey unique autoIncrement"` // The unique ID of this alert
+	UserId        string        `gorm:"index"`
+	User          User          `gorm:"references:ID"`
+	ItemId        string        `gorm:"index"`
+	Item          Item          `gorm:"references:ID"`
+	OrderType     string        // The order type of this alert ()
+	PriceMode     string        // The price mode of this alert ()
+	LowerPrice    uint32        // The minimum price of this alert (inclusive)
+	UpperPrice    uint32        // The maximum price of this alert (inclusive)
+	PriceVariance uint32        // The price variance to trigger the alert
+	Platform      string        // The platform this alert applies to
+	ModRank       sql.NullInt32 // The rank of the orders this alert watches, any rank if not set
+	Hits          int32         `gorm:"'type:Int4' 'default:0'"`
+	Active        bool          `gorm:"default:true"`
+	TriggeredAt   sql.NullTime  // When the alert last matched an order
 }
 
 // A struct to represent a trade
 type Trade struct {
 	gorm.Model
-	ID     string `gorm:"primaryKey unique autoIncrement"` // The unique ID of this trade
-	UserId string `gorm:"index"`
-	ItemId string `gorm:"index"`
-	Item   Item   `gorm:"references:ID"`
-	Kind   uint8
-	Price  uint32 `gorm:"type:Int4"` // The price of this trade
+	ID      string `gorm:"primaryKey unique autoIncrement"` // The unique ID of this trade
+	UserId  string `gorm:"index"`
+	ItemId  string `gorm:"index"`
+	Item    Item   `gorm:"references:ID"`
+	Kind    uint8
+	Price   uint32        `gorm:"type:Int4"` // The price of this trade
+	ModRank sql.NullInt32 // The rank of the item traded, for items which can be ranked
+	Outlier string        `gorm:"index"` // Why the order is left out of stats and alerts, see OutlierReason
 }
 
 // A struct to represent an item from the API
@@ -126,6 +162,90 @@ type StateInfo struct {
 	DeepSynced time.Time
 }
 
+// A struct to represent a private message sent through the warframe.market socket
+type OutboxMessage struct {
+	gorm.Model
+	ChatID    string         `gorm:"index"` // The chat the message is sent in
+	Message   string         // The text of the message
+	Status    string         `gorm:"index"` // See OutboxStatus
+	TempID    sql.NullString // The temporary ID of the most recent attempt, used to match the confirmation
+	Attempts  int32          `gorm:"'type:Int4' 'default:0'"`
+	SentAt    sql.NullTime   // When the most recent attempt was sent
+	LastError sql.NullString // Why the most recent attempt was not confirmed
+}
+
+// A struct to represent a warframe.market account linked to a Discord user. Users may link one account per platform.
+type LinkedAccount struct {
+	gorm.Model
+	UserID      string    `gorm:"uniqueIndex:idx_linked_accounts_user_platform"` // The Discord user the account is linked to
+	Platform    string    `gorm:"uniqueIndex:idx_linked_accounts_user_platform"`
+	WfmID       string    `gorm:"uniqueIndex"`
+	Username    string    // The in-game name of the account, kept up to date as it changes
+	VerifiedAt  time.Time // When the user proved they own the account
+	IsDefault   bool      // The account used when the user doesn't name a platform, mirrored on the User
+	Reputation  int32     `gorm:"'type:Int4' 'default:0'"`
+	Banned      bool      // Set once warframe.market bans the account
+	Stale       bool      // Set when the profile can't be found under its name, until its new name is seen
+	LastSeen    sql.NullTime
+	RefreshedAt sql.NullTime `gorm:"index"` // When the profile was last read from warframe.market
+}
+
+// A struct to represent the reputation of a linked warframe.market account at a point in time
+type ReputationRecord struct {
+	ID         uint      `gorm:"primaryKey"`
+	WfmID      string    `gorm:"index"`
+	Reputation int32     `gorm:"'type:Int4'"`
+	RecordedAt time.Time `gorm:"index"`
+}
+
+// A struct to represent a Discord channel subscribed to a feed, such as new set flips. Guilds may subscribe once per feed.
+type GuildSubscription struct {
+	gorm.Model
+	GuildID   string `gorm:"uniqueIndex:idx_guild_subscriptions_guild_kind"`
+	Kind      string `gorm:"uniqueIndex:idx_guild_subscriptions_guild_kind"` // See SubscriptionKind
+	ChannelID string // The channel the feed is posted in
+	MinMargin int32  `gorm:"'type:Int4' 'default:0'"` // The least platinum an opportunity must make to be posted
+}
+
+// A struct to represent a single-use code, which links the warframe.market account that sends it to a Discord user
+type LinkCode struct {
+	gorm.Model
+	Code      string       `gorm:"uniqueIndex"`
+	UserID    string       `gorm:"index"` // The Discord user the code was issued to
+	ExpiresAt time.Time    // When the code can no longer be redeemed
+	UsedAt    sql.NullTime // When the code was redeemed
+	UsedBy    sql.NullString
+}
+
+// A struct to represent a Discord user's progress through linking their warframe.market account
+type LinkSession struct {
+	gorm.Model
+	UserID           string         `gorm:"index"` // The Discord user linking their account
+	State            string         `gorm:"index"` // See linking.State
+	Username         sql.NullString // The warframe.market username the user entered
+	ProfileID        sql.NullString // The WFM ID of the profile the user chose
+	ProfileName      sql.NullString
+	ProfileLocale    sql.NullString
+	ProfilePlatform  sql.NullString
+	ProfileLastSeen  sql.NullTime
+	LinkCodeID       sql.NullInt64  // The link code issued to the user
+	Code             sql.NullString // The text of the link code, to show it again
+	Method           sql.NullString // How the user verifies the profile, see linking.Method
+	InteractionAppID sql.NullString // The Discord interaction showing the flow, used to edit it
+	InteractionToken sql.NullString
+	InteractionAt    sql.NullTime // When the interaction was created, as its token only lasts 15 minutes
+	ExpiresAt        time.Time
+}
+
+// A struct to represent the wrong link codes sent by a warframe.market user
+type LinkLockout struct {
+	WfmID         string       `gorm:"primaryKey"`
+	Failures      int32        `gorm:"'type:Int4' 'default:0'"`
+	LastFailureAt sql.NullTime // When the last wrong code was sent, as old failures are forgotten
+	LockedUntil   sql.NullTime
+	UpdatedAt     time.Time
+}
+
 // A struct to represent a trade stat -
 // This is only used to represent trade data in our time series db hypertable
 // We must run a seperate query to get the trade data from the table, as well as
@@ -139,4 +259,6 @@ type TradeInfo struct {
 	ItemId      string    `gorm:"primaryKey"`
 	Price       uint32
 	IsSellOrder bool
+	ModRank     sql.NullInt32 // The rank of the item traded, for items which can be ranked
+	Outlier     bool          `gorm:"default:false"` // Outliers are kept, but left out of stats
 }
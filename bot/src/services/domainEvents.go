@@ -0,0 +1,71 @@
+package services
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// DomainEventKind is something a user did, which other parts of the bot may react to
+type DomainEventKind string
+
+const (
+	DomainAccountLinked  = DomainEventKind("account_linked")  // The user linked a warframe.market account
+	DomainAlertTriggered = DomainEventKind("alert_triggered") // One of the user's price alerts matched an order
+	DomainPriceChecked   = DomainEventKind("price_checked")   // The user checked the price of an item
+)
+
+// DomainEvent describes a single thing a user did
+type DomainEvent struct {
+	Kind   DomainEventKind
+	UserID string
+	At     time.Time
+}
+
+// EventBus delivers domain events to every handler subscribed to their kind
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[DomainEventKind][]func(event DomainEvent)
+}
+
+var Bus = &EventBus{handlers: map[DomainEventKind][]func(event DomainEvent){}}
+
+// Subscribe calls the handler for every event of the given kind
+func (b *EventBus) Subscribe(kind DomainEventKind, handler func(event DomainEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[kind] = append(b.handlers[kind], handler)
+}
+
+// Publish delivers the event in the background, so the publisher is never slowed down by its handlers.
+// Handlers of the same event are called one after another, in the order they subscribed.
+func (b *EventBus) Publish(kind DomainEventKind, userID string) {
+	event := DomainEvent{Kind: kind, UserID: userID, At: time.Now()}
+
+	b.mu.RLock()
+	handlers := b.handlers[kind]
+	b.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		return
+	}
+
+	go func() {
+		for _, handler := range handlers {
+			b.deliver(handler, event)
+		}
+	}()
+}
+
+// deliver calls a single handler, so that one failing handler doesn't affect the others
+func (b *EventBus) deliver(handler func(event DomainEvent), event DomainEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Domain event handler panicked on %s: %v\n%s", event.Kind, r, debug.Stack())
+		}
+	}()
+
+	handler(event)
+}
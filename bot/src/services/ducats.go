@@ -0,0 +1,136 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// Ducat prices are read from the books the set analyzer keeps fresh, which are on this platform
+const DucatPlatform = SetArbitragePlatform
+
+// Baro Ki'Teer's stock is commonly valued at this many ducats per platinum, so parts doing better are worth trading in
+const DucatsPerPlatinumWorth = 10.0
+
+// DucatFilter narrows down the items ranked by ducats per platinum
+type DucatFilter struct {
+	Vaulted      *bool  // Only vaulted or unvaulted items, or both if nil
+	Part         string // Only items whose slug names this part, e.g. "neuroptics" or "blueprint"
+	MinLiquidity int    // Only items with at least this many for sale
+}
+
+// DucatValue is what an item is worth in ducats, compared to its price
+type DucatValue struct {
+	Item      Item
+	Price     int     // The cheapest sell order, ignoring outliers
+	Liquidity int     // How many are for sale
+	Ratio     float64 // Ducats per platinum
+}
+
+// TradeForDucats returns true if the item is worth more to Baro than to other players.
+// Items without a price can't be judged, so they aren't.
+func (v *DucatValue) TradeForDucats() bool {
+	return v.Price > 0 && v.Ratio >= DucatsPerPlatinumWorth
+}
+
+// DucatSale is what selling a number of an item to other players earns, compared to trading them for ducats
+type DucatSale struct {
+	Item     Item
+	Quantity int
+	Total    int     // What selling all of them to the best buy orders earns, or 0 if there aren't enough buyers
+	Ratio    float64 // Ducats given up per platinum earned
+}
+
+// Known returns true if there are enough buyers to tell what the items sell for
+func (s *DucatSale) Known() bool {
+	return s.Total > 0
+}
+
+// TradeForDucats returns true if the items are worth more to Baro than to the buyers
+func (s *DucatSale) TradeForDucats() bool {
+	return s.Known() && s.Ratio >= DucatsPerPlatinumWorth
+}
+
+// RankDucats returns up to limit items matching the filter, the most ducats per platinum first.
+// Items without a sell order in the order book are left out, as their price isn't known yet.
+func (db *Database) RankDucats(filter DucatFilter, limit int) ([]DucatValue, error) {
+	var items []Item
+
+	query := db.Inner.Where("ducats > 0")
+
+	if filter.Vaulted != nil {
+		query = query.Where("vaulted = ?", *filter.Vaulted)
+	}
+
+	err := query.Find(&items).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var values []DucatValue
+
+	for _, item := range items {
+		if filter.Part != "" && !hasPart(item.Slug, filter.Part) {
+			continue
+		}
+
+		value := GetDucatValue(item)
+
+		if value.Price == 0 || value.Liquidity < filter.MinLiquidity {
+			continue
+		}
+
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(a, b int) bool {
+		if values[a].Ratio != values[b].Ratio {
+			return values[a].Ratio > values[b].Ratio
+		}
+
+		return values[a].Liquidity > values[b].Liquidity
+	})
+
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+
+	return values, nil
+}
+
+// GetDucatValue compares the ducats of the item against its cheapest sell order in the order book
+func GetDucatValue(item Item) DucatValue {
+	sells, _ := Orders.Clean(item.ID, DucatPlatform, nil, string(OrderTypeSell))
+	value := DucatValue{Item: item, Liquidity: totalQuantity(sells)}
+
+	if len(sells) > 0 {
+		value.Price = sells[0].Price
+		value.Ratio = float64(item.Ducats) / float64(value.Price)
+	}
+
+	return value
+}
+
+// GetDucatSale prices selling the quantity of the item to its best buy orders in the order book, walking down
+// the buyers until they have taken all of them
+func GetDucatSale(item Item, quantity int) DucatSale {
+	buys, _ := Orders.Clean(item.ID, DucatPlatform, nil, string(OrderTypeBuy))
+	sale := DucatSale{Item: item, Quantity: quantity}
+
+	if total, ok := fill(buys, quantity); ok && total > 0 {
+		sale.Total = total
+		sale.Ratio = float64(int(item.Ducats)*quantity) / float64(total)
+	}
+
+	return sale
+}
+
+// hasPart returns true if the slug names the part, e.g. "ash_prime_neuroptics" names "neuroptics".
+// Blueprints are only the main blueprint, as the slugs of part blueprints leave the word out.
+func hasPart(slug string, part string) bool {
+	if part == "blueprint" {
+		return strings.HasSuffix(slug, "_blueprint")
+	}
+
+	return strings.Contains(slug+"_", "_"+part+"_")
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// The feeds a guild channel can subscribe to
+type SubscriptionKind string
+
+const (
+	SubscriptionSetFlips = SubscriptionKind("set_flips") // Sets which can be built or split for a profit
+)
+
+// SubscribeGuild posts the feed in the channel, replacing the guild's previous channel for it
+func (db *Database) SubscribeGuild(guildID string, kind SubscriptionKind, channelID string, minMargin int) error {
+	return db.Inner.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "guild_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channel_id", "min_margin", "updated_at"}),
+	}).Create(&GuildSubscription{
+		GuildID:   guildID,
+		Kind:      string(kind),
+		ChannelID: channelID,
+		MinMargin: int32(minMargin),
+	}).Error
+}
+
+// UnsubscribeGuild stops posting the feed in the guild, returning false if it wasn't subscribed
+func (db *Database) UnsubscribeGuild(guildID string, kind SubscriptionKind) (bool, error) {
+	// The row is removed for good, as it holds the unique guild and kind
+	tx := db.Inner.Unscoped().Where("guild_id = ? AND kind = ?", guildID, string(kind)).Delete(&GuildSubscription{})
+
+	return tx.RowsAffected > 0, tx.Error
+}
+
+// GetGuildSubscriptions returns every channel subscribed to the feed
+func (db *Database) GetGuildSubscriptions(kind SubscriptionKind) ([]GuildSubscription, error) {
+	var subscriptions []GuildSubscription
+
+	err := db.Inner.Where("kind = ?", string(kind)).Find(&subscriptions).Error
+
+	return subscriptions, err
+}
@@ -1,86 +1,122 @@
 package services
 
 import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
 	"github.com/titanous/json5"
-	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+// I18n holds every loaded language.
+// The languages are never modified once loaded, reloading swaps in a new map under the lock instead.
 type I18n struct {
 	Languages map[string]Language
+	mu        sync.RWMutex
+	reloading sync.Mutex // Held for a whole reload, so the watcher and the admin command don't swap at the same time
+	path      string
+	files     map[string]languageFile
+}
+
+// languageFile tracks the language loaded from a file, so that changes can be detected
+type languageFile struct {
+	ISO     string
+	ModTime time.Time
 }
 
 func NewI18n() *I18n {
 	return &I18n{
 		Languages: map[string]Language{},
+		files:     map[string]languageFile{},
 	}
 }
 
+// Load reads every language file in the directory. Any file which fails to load is fatal to the whole load.
 func (i *I18n) Load(path string) error {
-	files, err := os.ReadDir(path)
+	i.path = path
+
+	report, err := i.reload(true)
 
 	if err != nil {
 		return err
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+	if len(report.Failed) > 0 {
+		return report.Failed[0].Err
+	}
 
-		if file.Name() == "contributing.md" {
-			continue
-		}
+	return nil
+}
 
-		handle, err := os.Open(path + "/" + file.Name())
+// snapshot returns the current set of languages, which is safe to read without holding the lock
+func (i *I18n) snapshot() map[string]Language {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 
-		if err != nil {
-			return err
-		}
+	return i.Languages
+}
 
-		data, err := io.ReadAll(handle)
+// Language returns the language with exactly the given ISO code
+func (i *I18n) Language(iso string) (Language, bool) {
+	lang, ok := i.snapshot()[iso]
+	return lang, ok
+}
 
-		if err != nil {
-			return err
-		}
+// ISOs returns the sorted ISO codes of every loaded language
+func (i *I18n) ISOs() []string {
+	languages := i.snapshot()
+	isos := make([]string, 0, len(languages))
 
-		j5 := &RawLanguage{}
+	for iso := range languages {
+		isos = append(isos, iso)
+	}
 
-		err = json5.Unmarshal(data, j5)
+	sort.Strings(isos)
 
-		if err != nil {
-			return err
-		}
+	return isos
+}
 
-		final := j5.Finalize()
+// LoadLanguageFile reads and parses a single language file
+func LoadLanguageFile(path string) (Language, error) {
+	data, err := os.ReadFile(path)
 
-		i.Languages[final.ISO] = final
+	if err != nil {
+		return Language{}, err
 	}
 
-	return nil
-}
+	j5 := RawLanguage{}
 
-type RawLanguage map[string]interface{}
+	err = json5.Unmarshal(data, &j5)
 
-type Param struct {
-	Start int
-	End   int
-	Name  string
-}
+	if err != nil {
+		return Language{}, fmt.Errorf("%s: %w", path, err)
+	}
 
-type Snippet struct {
-	RawText string
-	Params  []Param
+	language, err := j5.Finalize()
+
+	if err != nil {
+		return Language{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return language, nil
 }
 
+type RawLanguage map[string]interface{}
+
 type Language struct {
 	ISO          string
 	Name         string
 	Maintainer   string
+	Keys         []string
 	Bindings     map[string]Snippet
+	Lists        map[string][]Snippet
 	Measurements LanguageMeasurements
 	Time         LanguageTime
+	Numbers      LanguageNumbers
 }
 
 type LanguageMeasurements struct {
@@ -115,147 +151,283 @@ type LanguageMeasurement struct {
 	Multiplier float64
 }
 
-func SnippetFromString(s string) Snippet {
-	RawText := s
-	var Params []Param
+// Finalize converts the raw key/value pairs of a language file into a language.
+// Every string value is parsed into a snippet, and every array of strings into a list of snippets.
+// Any value which can't be parsed is reported, rather than silently dropped.
+func (l RawLanguage) Finalize() (Language, error) {
+	iso, ok := l["meta.iso"].(string)
 
-	for index, char := range RawText {
-		if char == '%' {
-			start := index
-			end := index + 1
-			for end < len(RawText) && RawText[end] != '%' {
-				end++
-			}
-			name := RawText[start+1 : end]
-			Params = append(Params, Param{
-				Start: start,
-				End:   end,
-				Name:  name,
-			})
-		}
+	if !ok || iso == "" {
+		return Language{}, fmt.Errorf("missing required key 'meta.iso'")
 	}
 
-	return Snippet{
-		RawText: RawText,
-		Params:  Params,
-	}
-}
+	name := l.String("meta.name")
 
-func (l RawLanguage) Finalize() Language {
+	if name == "" {
+		name = iso
+	}
 
 	language := Language{
-		ISO:        l["meta.iso"].(string),
-		Name:       l["name"].(string),
-		Maintainer: l["maintainer"].(string),
+		ISO:        iso,
+		Name:       name,
+		Maintainer: l.String("meta.maintainer"),
 		Bindings:   map[string]Snippet{},
+		Lists:      map[string][]Snippet{},
 		Measurements: LanguageMeasurements{
 			Meter: LanguageMeasurement{
-				Name:       l["units.distance.name.meter"].(string),
-				Multiplier: l["units.distance.multiplier.meter"].(float64),
+				Name:       l.String("units.distance.name.meter"),
+				Multiplier: l.Number("units.distance.multiplier.meter"),
 			},
 			Centimeter: LanguageMeasurement{
-				Name:       l["units.distance.name.centimeter"].(string),
-				Multiplier: l["units.distance.multiplier.centimeter"].(float64),
+				Name:       l.String("units.distance.name.centimeter"),
+				Multiplier: l.Number("units.distance.multiplier.centimeter"),
 			},
 			Millimeter: LanguageMeasurement{
-				Name:       l["units.distance.name.millimeter"].(string),
-				Multiplier: l["units.distance.multiplier.millimeter"].(float64),
+				Name:       l.String("units.distance.name.millimeter"),
+				Multiplier: l.Number("units.distance.multiplier.millimeter"),
 			},
 		},
 		Time: LanguageTime{
-			Second:        l["units.time.second"].(string),
-			SecondsPlural: l["units.time.seconds"].(string),
-			Minute:        l["units.time.minute"].(string),
-			MinutesPlural: l["units.time.minutes"].(string),
-			Hour:          l["units.time.hour"].(string),
-			HoursPlural:   l["units.time.hours"].(string),
-			Day:           l["units.time.day"].(string),
-			DaysPlural:    l["units.time.days"].(string),
-			Week:          l["units.time.week"].(string),
-			WeeksPlural:   l["units.time.weeks"].(string),
-			Month:         l["units.time.month"].(string),
-			MonthsPlural:  l["units.time.months"].(string),
-			Year:          l["units.time.year"].(string),
-			YearsPlural:   l["units.time.years"].(string),
+			Second:        l.String("units.time.second"),
+			SecondsPlural: l.String("units.time.seconds"),
+			Minute:        l.String("units.time.minute"),
+			MinutesPlural: l.String("units.time.minutes"),
+			Hour:          l.String("units.time.hour"),
+			HoursPlural:   l.String("units.time.hours"),
+			Day:           l.String("units.time.day"),
+			DaysPlural:    l.String("units.time.days"),
+			Week:          l.String("units.time.week"),
+			WeeksPlural:   l.String("units.time.weeks"),
+			Month:         l.String("units.time.month"),
+			MonthsPlural:  l.String("units.time.months"),
+			Year:          l.String("units.time.year"),
+			YearsPlural:   l.String("units.time.years"),
+		},
+		Numbers: LanguageNumbers{
+			ThousandsSeparator: l.String("units.number.thousands_separator"),
+			DecimalSeparator:   l.String("units.number.decimal_separator"),
 		},
 	}
 
-	for name, value := range l {
-		switch name {
-		case "meta.iso":
-		case "meta.name":
-		case "meta.maintainer":
-		case "units.distance.name.meter":
-		case "units.distance.multiplier.meter":
-		case "units.distance.name.centimeter":
-		case "units.distance.multiplier.centimeter":
-		case "units.distance.name.millimeter":
-		case "units.distance.multiplier.millimeter":
-		case "units.time.second":
-		case "units.time.seconds":
-		case "units.time.minute":
-		case "units.time.minutes":
-		case "units.time.hour":
-		case "units.time.hours":
-		case "units.time.day":
-		case "units.time.days":
-		case "units.time.week":
-		case "units.time.weeks":
-		case "units.time.month":
-		case "units.time.months":
-		case "units.time.year":
-		case "units.time.years":
+	var problems []string
+
+	for key, value := range l {
+		language.Keys = append(language.Keys, key)
+
+		switch v := value.(type) {
+		case string:
+			snippet, err := SnippetFromString(v)
+
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", key, err))
+				continue
+			}
+
+			language.Bindings[key] = snippet
+		case []interface{}:
+			list := make([]Snippet, 0, len(v))
+
+			for index, entry := range v {
+				text, ok := entry.(string)
+
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s[%d]: expected a string, found %T", key, index, entry))
+					continue
+				}
+
+				snippet, err := SnippetFromString(text)
+
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("%s[%d]: %s", key, index, err))
+					continue
+				}
+
+				list = append(list, snippet)
+			}
+
+			language.Lists[key] = list
+		case float64:
+			// Numeric values (e.g. unit multipliers) are read directly from the raw language above
 			continue
 		default:
-			switch v := value.(type) {
-			case string:
-				language.Bindings[name] = SnippetFromString(v)
-			default:
-				log.Printf("Value for key is not valid - %s", name)
-			}
+			problems = append(problems, fmt.Sprintf("%s: unsupported value of type %T", key, value))
 		}
+	}
+
+	sort.Strings(language.Keys)
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return Language{}, fmt.Errorf("invalid values in language '%s':\n  %s", iso, strings.Join(problems, "\n  "))
+	}
+
+	return language, nil
+}
 
+// String returns the string value of the given key, or an empty string if it is missing or not a string
+func (l RawLanguage) String(key string) string {
+	if value, ok := l[key].(string); ok {
+		return value
 	}
 
-	return language
+	return ""
+}
+
+// Number returns the numeric value of the given key, or zero if it is missing or not a number
+func (l RawLanguage) Number(key string) float64 {
+	if value, ok := l[key].(float64); ok {
+		return value
+	}
+
+	return 0
+}
+
+// language Returns the best matching language for the given locale, which is the default language if nothing matches
+func language(languages map[string]Language, iso *string) Language {
+	if iso == nil {
+		return languages[DefaultLocale]
+	}
+
+	return languages[resolve(languages, *iso)]
+}
+
+// lookup Returns the snippet bound to the key in the given language, falling back to the default language
+func lookup(languages map[string]Language, lang Language, key string) (Snippet, bool) {
+	if snippet, ok := lang.Bindings[key]; ok {
+		return snippet, true
+	}
+
+	snippet, ok := languages[DefaultLocale].Bindings[key]
+
+	return snippet, ok
+}
+
+// language Returns the best matching language for the given locale, which is the default language if nothing matches
+func (i *I18n) language(iso *string) Language {
+	return language(i.snapshot(), iso)
 }
 
 // Get Returns the value of the given key in the language, or an empty string if the key is not found.
+// Keys which are missing from the language fall back to the default language.
 func (i *I18n) Get(iso *string, key string, params *map[string]interface{}) string {
-	// Check if the language is present (or select the default)
-	var lang Language
-	if iso != nil {
-		if l, ok := i.Languages[*iso]; ok {
-			lang = l
-		} else {
-			lang = i.Languages["en-US"]
-		}
-	} else {
-		lang = i.Languages["en-US"]
-	}
+	languages := i.snapshot()
+	lang := language(languages, iso)
 
-	snippet, ok := lang.Bindings[key]
+	snippet, ok := lookup(languages, lang, key)
 
 	if !ok {
 		return ""
 	}
 
-	result := snippet.RawText
+	if params == nil {
+		params = &map[string]interface{}{}
+	}
+
+	return snippet.Format(&lang, *params)
+}
+
+// GetList Returns every value of the given array key in the language, or nil if the key is not found.
+// Keys which are missing from the language fall back to the default language.
+func (i *I18n) GetList(iso *string, key string, params *map[string]interface{}) []string {
+	languages := i.snapshot()
+	lang := language(languages, iso)
+
+	list, ok := lang.Lists[key]
+
+	if !ok {
+		list, ok = languages[DefaultLocale].Lists[key]
+	}
+
+	if !ok {
+		return nil
+	}
 
 	if params == nil {
 		params = &map[string]interface{}{}
 	}
 
-	// Replace params with values where possible or leave as is if value is not provided.
-	for _, param := range snippet.Params {
-		if (*params)[param.Name] == nil {
+	values := make([]string, 0, len(list))
+
+	for _, snippet := range list {
+		values = append(values, snippet.Format(&lang, *params))
+	}
+
+	return values
+}
+
+// DefaultLocale is the language used when no better match can be found
+const DefaultLocale = "en-US"
+
+// Resolve Returns the ISO code of the first candidate which matches a loaded language.
+// Candidates match either exactly ("de-DE"), or by their base language ("de" matches "de-DE").
+// Empty candidates are skipped, and the default locale is returned if nothing matches.
+func (i *I18n) Resolve(candidates ...string) string {
+	return resolve(i.snapshot(), candidates...)
+}
+
+func resolve(languages map[string]Language, candidates ...string) string {
+	for _, candidate := range candidates {
+		if candidate == "" {
 			continue
-		} else {
-			result = result[:param.Start] + (*params)[param.Name].(string) + result[param.End:]
+		}
+
+		if _, ok := languages[candidate]; ok {
+			return candidate
+		}
+
+		base := baseLanguage(candidate)
+
+		// Prefer the default locale when it shares the same base language
+		if baseLanguage(DefaultLocale) == base {
+			return DefaultLocale
+		}
+
+		for iso := range languages {
+			if baseLanguage(iso) == base {
+				return iso
+			}
+		}
+	}
+
+	return DefaultLocale
+}
+
+// Localizations Returns the value of the given key for every Discord locale which is covered by a loaded language.
+// Locales without a matching language, or languages which do not define the key, are left out.
+func (i *I18n) Localizations(key string) map[discordgo.Locale]string {
+	languages := i.snapshot()
+	localizations := map[discordgo.Locale]string{}
+
+	for locale := range discordgo.Locales {
+		if locale == discordgo.Unknown {
+			continue
+		}
+
+		lang, ok := languages[string(locale)]
+
+		if !ok {
+			// Fall back to any language which shares the same base language (e.g. "de" for "de-DE")
+			base := baseLanguage(string(locale))
+
+			for iso, l := range languages {
+				if baseLanguage(iso) == base {
+					lang = l
+					ok = true
+					break
+				}
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		if snippet, found := lang.Bindings[key]; found {
+			localizations[locale] = snippet.RawText
 		}
 	}
 
-	return result
+	return localizations
 }
 
 var LanguageManager *I18n
@@ -267,4 +439,12 @@ func InitI18n() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	for _, report := range LanguageManager.Validate() {
+		if report.IsClean() {
+			continue
+		}
+
+		log.Printf("Language %s has problems:\n%s", report.Language, report)
+	}
 }
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LanguageNumbers describes how numbers are written in a language
+type LanguageNumbers struct {
+	// The separator between groups of thousands, e.g. "," in "1,234"
+	ThousandsSeparator string
+	// The separator between the integer and the fraction, e.g. "." in "1.5"
+	DecimalSeparator string
+}
+
+// A single unit of time, with its length and localized names
+type timeUnit struct {
+	length   time.Duration
+	singular string
+	plural   string
+}
+
+// timeUnits returns the units of time in the language, from the largest to the smallest
+func (l *Language) timeUnits() []timeUnit {
+	day := 24 * time.Hour
+
+	return []timeUnit{
+		{365 * day, l.Time.Year, l.Time.YearsPlural},
+		{30 * day, l.Time.Month, l.Time.MonthsPlural},
+		{7 * day, l.Time.Week, l.Time.WeeksPlural},
+		{day, l.Time.Day, l.Time.DaysPlural},
+		{time.Hour, l.Time.Hour, l.Time.HoursPlural},
+		{time.Minute, l.Time.Minute, l.Time.MinutesPlural},
+		{time.Second, l.Time.Second, l.Time.SecondsPlural},
+	}
+}
+
+// FormatNumber writes n with the given number of decimals, using the separators of the language
+func (l *Language) FormatNumber(n float64, decimals int) string {
+	thousands := l.Numbers.ThousandsSeparator
+	decimal := l.Numbers.DecimalSeparator
+
+	if decimal == "" {
+		decimal = "."
+	}
+
+	negative := n < 0
+	raw := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+
+	integer, fraction, _ := strings.Cut(raw, ".")
+
+	var builder strings.Builder
+
+	if negative {
+		builder.WriteString("-")
+	}
+
+	for index, digit := range integer {
+		if index > 0 && (len(integer)-index)%3 == 0 {
+			builder.WriteString(thousands)
+		}
+
+		builder.WriteRune(digit)
+	}
+
+	if fraction != "" {
+		builder.WriteString(decimal)
+		builder.WriteString(fraction)
+	}
+
+	return builder.String()
+}
+
+// FormatDuration writes the duration using its largest whole unit, e.g. "2 minutes" or "3 days"
+func (l *Language) FormatDuration(d time.Duration) string {
+	d = d.Abs()
+	unit := l.largestUnit(d)
+	count := int64(d / unit.length)
+	name := unit.plural
+
+	if PluralCategoryFor(l.ISO, float64(count)) == PluralOne {
+		name = unit.singular
+	}
+
+	return l.FormatNumber(float64(count), 0) + " " + name
+}
+
+// largestUnit returns the largest unit of time which fits in the duration, or seconds if none do
+func (l *Language) largestUnit(d time.Duration) timeUnit {
+	units := l.timeUnits()
+
+	for _, unit := range units {
+		if d >= unit.length {
+			return unit
+		}
+	}
+
+	return units[len(units)-1]
+}
+
+// roundDuration rounds the duration to the nearest whole unit it is written in, e.g. 14m59.9s to 15m
+func (l *Language) roundDuration(d time.Duration) time.Duration {
+	rounded := d.Abs().Round(l.largestUnit(d.Abs()).length)
+
+	if d < 0 {
+		return -rounded
+	}
+
+	return rounded
+}
+
+// FormatNumber writes n with the given number of decimals, using the separators of the language
+func (i *I18n) FormatNumber(iso *string, n float64, decimals int) string {
+	lang := i.language(iso)
+	return lang.FormatNumber(n, decimals)
+}
+
+// FormatDuration writes the duration using its largest whole unit, e.g. "2 minutes" or "3 days"
+func (i *I18n) FormatDuration(iso *string, d time.Duration) string {
+	lang := i.language(iso)
+	return lang.FormatDuration(d)
+}
+
+// FormatRelative writes the time relative to now, e.g. "in 2 minutes" or "3 days ago"
+func (i *I18n) FormatRelative(iso *string, t time.Time) string {
+	// The time has moved on a little since it was set, so round rather than truncate, e.g. an expiry 15 minutes away
+	// isn't written as "in 14 minutes" a moment later
+	lang := i.language(iso)
+	d := lang.roundDuration(time.Until(t))
+
+	if d.Abs() < time.Second {
+		return i.Get(iso, "units.time.relative.now", nil)
+	}
+
+	key := "units.time.relative.future"
+
+	if d < 0 {
+		key = "units.time.relative.past"
+	}
+
+	return i.Get(iso, key, &map[string]interface{}{
+		"Duration": i.FormatDuration(iso, d),
+	})
+}
+
+// FormatPlatinum writes an amount of platinum, e.g. "1,250 platinum"
+func (i *I18n) FormatPlatinum(iso *string, amount int64) string {
+	return i.Get(iso, "units.currency.platinum", &map[string]interface{}{
+		"Amount": i.FormatNumber(iso, float64(amount), 0),
+		"Count":  amount,
+	})
+}
+
+// FormatDucats writes an amount of ducats, e.g. "45 ducats"
+func (i *I18n) FormatDucats(iso *string, amount int64) string {
+	return i.Get(iso, "units.currency.ducats", &map[string]interface{}{
+		"Amount": i.FormatNumber(iso, float64(amount), 0),
+		"Count":  amount,
+	})
+}
+
+// FormatPercentChange writes the relative change between two values, e.g. "+12.5%" or "-3.0%"
+func (i *I18n) FormatPercentChange(iso *string, from float64, to float64) string {
+	if from == 0 {
+		return i.Get(iso, "units.number.percent_change.unknown", nil)
+	}
+
+	change := (to - from) / from * 100
+	sign := "+"
+
+	if change < 0 {
+		sign = "-"
+	}
+
+	return i.Get(iso, "units.number.percent_change", &map[string]interface{}{
+		"Sign":  sign,
+		"Value": i.FormatNumber(iso, math.Abs(change), 1),
+	})
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type SnippetPartKind uint8
+
+const (
+	// Literal text
+	PartText SnippetPartKind = iota
+	// A named placeholder, written as %Name%
+	PartParam
+	// A plural block, written as {Name, plural, one {...} other {...}}
+	PartPlural
+	// An ordinal plural block, written as {Name, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}
+	PartOrdinal
+	// A select block, written as {Name, select, male {...} female {...} other {...}}
+	PartSelect
+	// The number of the enclosing plural block, written as #
+	PartNumber
+)
+
+// SnippetPart is a single piece of a parsed snippet
+type SnippetPart struct {
+	Kind SnippetPartKind
+	// The literal text of a text part
+	Text string
+	// The name of the parameter this part reads from
+	Name string
+	// The branches of a plural, ordinal, or select block, keyed by their selector (e.g. "one", "=0", "other")
+	Branches map[string]Snippet
+}
+
+type Snippet struct {
+	RawText string
+	Parts   []SnippetPart
+}
+
+// SnippetFromString parses a translation value into a snippet.
+//
+// Values support three kinds of dynamic content:
+//   - %Name% is replaced by the value of the parameter called Name (%% is a literal percent sign)
+//   - {Name, plural, one {# item} other {# items}} picks a branch using the plural rules of the language
+//   - {Name, select, pc {PC} other {Console}} picks a branch matching the value of the parameter
+//
+// Within a plural block, # is replaced by the formatted number.
+func SnippetFromString(s string) (Snippet, error) {
+	p := &snippetParser{input: []rune(s)}
+
+	parts, err := p.parse(false, false)
+
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return Snippet{
+		RawText: s,
+		Parts:   parts,
+	}, nil
+}
+
+// Params returns the sorted, unique names of every parameter referenced by the snippet
+func (s Snippet) Params() []string {
+	found := map[string]bool{}
+	s.collectParams(found)
+
+	names := make([]string, 0, len(found))
+
+	for name := range found {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (s Snippet) collectParams(found map[string]bool) {
+	for _, part := range s.Parts {
+		if part.Name != "" {
+			found[part.Name] = true
+		}
+
+		for _, branch := range part.Branches {
+			branch.collectParams(found)
+		}
+	}
+}
+
+// Format renders the snippet in the given language.
+// Parameters which are not provided are left in place, so that missing values are easy to spot.
+func (s Snippet) Format(lang *Language, params map[string]interface{}) string {
+	var builder strings.Builder
+	s.format(&builder, lang, params, nil)
+	return builder.String()
+}
+
+func (s Snippet) format(builder *strings.Builder, lang *Language, params map[string]interface{}, number *float64) {
+	for _, part := range s.Parts {
+		switch part.Kind {
+		case PartText:
+			builder.WriteString(part.Text)
+		case PartParam:
+			value, ok := params[part.Name]
+
+			if !ok {
+				builder.WriteString("%" + part.Name + "%")
+				continue
+			}
+
+			builder.WriteString(FormatValue(lang, value))
+		case PartNumber:
+			if number == nil {
+				builder.WriteString("#")
+				continue
+			}
+
+			builder.WriteString(FormatValue(lang, *number))
+		case PartPlural, PartOrdinal:
+			value, ok := params[part.Name]
+			n, isNumber := toNumber(value)
+
+			if !ok || !isNumber {
+				part.Branches["other"].format(builder, lang, params, nil)
+				continue
+			}
+
+			var category PluralCategory
+
+			if part.Kind == PartOrdinal {
+				category = OrdinalCategoryFor(lang.ISO, n)
+			} else {
+				category = PluralCategoryFor(lang.ISO, n)
+			}
+
+			branch := selectPluralBranch(part.Branches, n, category)
+			branch.format(builder, lang, params, &n)
+		case PartSelect:
+			value, ok := params[part.Name]
+			branch, found := part.Branches[fmt.Sprint(value)]
+
+			if !ok || !found {
+				branch = part.Branches["other"]
+			}
+
+			branch.format(builder, lang, params, number)
+		}
+	}
+}
+
+// selectPluralBranch picks the exact match (e.g. "=0") if present, then the plural category, and finally "other"
+func selectPluralBranch(branches map[string]Snippet, n float64, category PluralCategory) Snippet {
+	if branch, ok := branches["="+strconv.FormatFloat(n, 'f', -1, 64)]; ok {
+		return branch
+	}
+
+	if branch, ok := branches[string(category)]; ok {
+		return branch
+	}
+
+	return branches["other"]
+}
+
+// FormatValue converts any parameter value to a string suitable for display in the given language
+func FormatValue(lang *Language, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Duration:
+		// Durations implement Stringer, but "1h2m3s" isn't meant for users
+		if lang != nil {
+			return lang.FormatDuration(v)
+		}
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC1123)
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	if n, ok := toNumber(value); ok {
+		return formatFloat(lang, n)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// formatFloat writes n with as many decimals as it needs, using the separators of the language if known
+func formatFloat(lang *Language, n float64) string {
+	raw := strconv.FormatFloat(n, 'f', -1, 64)
+
+	if n == math.Trunc(n) && math.Abs(n) < 1e15 {
+		raw = strconv.FormatInt(int64(n), 10)
+	}
+
+	if lang == nil {
+		return raw
+	}
+
+	_, fraction, _ := strings.Cut(raw, ".")
+
+	return lang.FormatNumber(n, len(fraction))
+}
+
+// toNumber converts any numeric value (or numeric string) to a float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+
+	return 0, false
+}
+
+type snippetParser struct {
+	input []rune
+	pos   int
+}
+
+// parse reads parts until the end of the input, or the closing brace of the enclosing branch
+func (p *snippetParser) parse(inBranch bool, inPlural bool) ([]SnippetPart, error) {
+	var parts []SnippetPart
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, SnippetPart{Kind: PartText, Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.input) {
+		char := p.input[p.pos]
+
+		switch {
+		case char == '%':
+			if p.peek(1) == '%' {
+				text.WriteRune('%')
+				p.pos += 2
+				continue
+			}
+
+			name, length := p.readName(p.pos + 1)
+
+			if length > 0 && p.peek(length+1) == '%' {
+				flush()
+				parts = append(parts, SnippetPart{Kind: PartParam, Name: name})
+				p.pos += length + 2
+				continue
+			}
+
+			text.WriteRune(char)
+			p.pos++
+		case char == '{':
+			block, ok, err := p.parseBlock()
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				text.WriteRune(char)
+				p.pos++
+				continue
+			}
+
+			flush()
+			parts = append(parts, block)
+		case char == '}' && inBranch:
+			flush()
+			return parts, nil
+		case char == '#' && inPlural:
+			flush()
+			parts = append(parts, SnippetPart{Kind: PartNumber})
+			p.pos++
+		default:
+			text.WriteRune(char)
+			p.pos++
+		}
+	}
+
+	if inBranch {
+		return nil, fmt.Errorf("unterminated branch, expected '}'")
+	}
+
+	flush()
+	return parts, nil
+}
+
+// parseBlock attempts to read a plural, ordinal, or select block at the current position.
+// If the brace does not start a block, ok is false and the brace should be treated as text.
+func (p *snippetParser) parseBlock() (SnippetPart, bool, error) {
+	start := p.pos
+	cursor := p.skipSpaces(start + 1)
+
+	name, length := p.readName(cursor)
+
+	if length == 0 {
+		return SnippetPart{}, false, nil
+	}
+
+	cursor = p.skipSpaces(cursor + length)
+
+	if p.at(cursor) != ',' {
+		return SnippetPart{}, false, nil
+	}
+
+	cursor = p.skipSpaces(cursor + 1)
+
+	kindName, length := p.readName(cursor)
+
+	var kind SnippetPartKind
+
+	switch kindName {
+	case "plural":
+		kind = PartPlural
+	case "selectordinal":
+		kind = PartOrdinal
+	case "select":
+		kind = PartSelect
+	default:
+		return SnippetPart{}, false, nil
+	}
+
+	cursor = p.skipSpaces(cursor + length)
+
+	if p.at(cursor) != ',' {
+		return SnippetPart{}, false, fmt.Errorf("expected ',' after '%s' in block '%s' at position %d", kindName, name, start)
+	}
+
+	p.pos = cursor + 1
+
+	part := SnippetPart{
+		Kind:     kind,
+		Name:     name,
+		Branches: map[string]Snippet{},
+	}
+
+	for {
+		p.pos = p.skipSpaces(p.pos)
+
+		if p.pos >= len(p.input) {
+			return SnippetPart{}, false, fmt.Errorf("unterminated block '%s' starting at position %d", name, start)
+		}
+
+		if p.input[p.pos] == '}' {
+			p.pos++
+			break
+		}
+
+		selector := p.readSelector()
+
+		if selector == "" {
+			return SnippetPart{}, false, fmt.Errorf("expected a selector in block '%s' at position %d", name, p.pos)
+		}
+
+		p.pos = p.skipSpaces(p.pos)
+
+		if p.at(p.pos) != '{' {
+			return SnippetPart{}, false, fmt.Errorf("expected '{' after selector '%s' in block '%s'", selector, name)
+		}
+
+		branchStart := p.pos + 1
+		p.pos++
+
+		parts, err := p.parse(true, kind != PartSelect)
+
+		if err != nil {
+			return SnippetPart{}, false, fmt.Errorf("in block '%s', branch '%s': %w", name, selector, err)
+		}
+
+		part.Branches[selector] = Snippet{
+			RawText: string(p.input[branchStart:p.pos]),
+			Parts:   parts,
+		}
+
+		// Skip the closing brace of the branch
+		p.pos++
+	}
+
+	if _, ok := part.Branches["other"]; !ok {
+		return SnippetPart{}, false, fmt.Errorf("block '%s' is missing the required 'other' branch", name)
+	}
+
+	return part, true, nil
+}
+
+func (p *snippetParser) readName(from int) (string, int) {
+	end := from
+
+	for end < len(p.input) {
+		char := p.input[end]
+
+		if unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_' || char == '.' {
+			end++
+			continue
+		}
+
+		break
+	}
+
+	return string(p.input[from:end]), end - from
+}
+
+func (p *snippetParser) readSelector() string {
+	start := p.pos
+
+	for p.pos < len(p.input) && !unicode.IsSpace(p.input[p.pos]) && p.input[p.pos] != '{' && p.input[p.pos] != '}' {
+		p.pos++
+	}
+
+	return string(p.input[start:p.pos])
+}
+
+func (p *snippetParser) skipSpaces(from int) int {
+	for from < len(p.input) && unicode.IsSpace(p.input[from]) {
+		from++
+	}
+
+	return from
+}
+
+func (p *snippetParser) at(index int) rune {
+	if index < 0 || index >= len(p.input) {
+		return 0
+	}
+
+	return p.input[index]
+}
+
+func (p *snippetParser) peek(offset int) rune {
+	return p.at(p.pos + offset)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"math"
+	"strings"
+)
+
+// PluralCategory is one of the CLDR plural categories
+// See https://www.unicode.org/cldr/charts/latest/supplemental/language_plural_rules.html
+type PluralCategory string
+
+const (
+	PluralZero  = PluralCategory("zero")
+	PluralOne   = PluralCategory("one")
+	PluralTwo   = PluralCategory("two")
+	PluralFew   = PluralCategory("few")
+	PluralMany  = PluralCategory("many")
+	PluralOther = PluralCategory("other")
+)
+
+// PluralRule maps a number to its plural category.
+// i is the integer part of the number, and hasFraction is true if the number has visible fraction digits.
+type PluralRule func(n float64, i int64, hasFraction bool) PluralCategory
+
+// Cardinal plural rules, keyed by base language.
+// Languages which are not listed here use the English rules.
+var PluralRules = map[string]PluralRule{
+	"en": pluralRuleOneOther,
+	"de": pluralRuleOneOther,
+	"sv": pluralRuleOneOther,
+	"nl": pluralRuleOneOther,
+	"it": pluralRuleOneOther,
+	"es": func(n float64, i int64, hasFraction bool) PluralCategory {
+		if n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	},
+	"pt": func(n float64, i int64, hasFraction bool) PluralCategory {
+		if i == 0 || i == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	},
+	"fr": func(n float64, i int64, hasFraction bool) PluralCategory {
+		if i == 0 || i == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	},
+	"ru": pluralRuleEastSlavic,
+	"uk": pluralRuleEastSlavic,
+	"pl": func(n float64, i int64, hasFraction bool) PluralCategory {
+		if hasFraction {
+			return PluralOther
+		}
+
+		mod10, mod100 := i%10, i%100
+
+		switch {
+		case i == 1:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		default:
+			return PluralMany
+		}
+	},
+	"cs": func(n float64, i int64, hasFraction bool) PluralCategory {
+		switch {
+		case hasFraction:
+			return PluralMany
+		case i == 1:
+			return PluralOne
+		case i >= 2 && i <= 4:
+			return PluralFew
+		default:
+			return PluralOther
+		}
+	},
+	"ko": pluralRuleOther,
+	"ja": pluralRuleOther,
+	"zh": pluralRuleOther,
+	"th": pluralRuleOther,
+	"vi": pluralRuleOther,
+}
+
+// Ordinal plural rules, keyed by base language.
+// Languages which are not listed here always use "other".
+var OrdinalRules = map[string]PluralRule{
+	"en": func(n float64, i int64, hasFraction bool) PluralCategory {
+		mod10, mod100 := i%10, i%100
+
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 == 2 && mod100 != 12:
+			return PluralTwo
+		case mod10 == 3 && mod100 != 13:
+			return PluralFew
+		default:
+			return PluralOther
+		}
+	},
+}
+
+// PluralCategoryFor returns the cardinal plural category of n in the given language
+func PluralCategoryFor(iso string, n float64) PluralCategory {
+	rule, ok := PluralRules[baseLanguage(iso)]
+
+	if !ok {
+		rule = pluralRuleOneOther
+	}
+
+	return applyPluralRule(rule, n)
+}
+
+// OrdinalCategoryFor returns the ordinal plural category of n in the given language
+func OrdinalCategoryFor(iso string, n float64) PluralCategory {
+	rule, ok := OrdinalRules[baseLanguage(iso)]
+
+	if !ok {
+		rule = pluralRuleOther
+	}
+
+	return applyPluralRule(rule, n)
+}
+
+func applyPluralRule(rule PluralRule, n float64) PluralCategory {
+	abs := math.Abs(n)
+	i := int64(math.Trunc(abs))
+
+	return rule(abs, i, abs != math.Trunc(abs))
+}
+
+func baseLanguage(iso string) string {
+	return strings.ToLower(strings.Split(iso, "-")[0])
+}
+
+func pluralRuleOneOther(n float64, i int64, hasFraction bool) PluralCategory {
+	if i == 1 && !hasFraction {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRuleOther(n float64, i int64, hasFraction bool) PluralCategory {
+	return PluralOther
+}
+
+func pluralRuleEastSlavic(n float64, i int64, hasFraction bool) PluralCategory {
+	if hasFraction {
+		return PluralOther
+	}
+
+	mod10, mod100 := i%10, i%100
+
+	switch {
+	case mod10 == 1 && mod100 != 11:
+		return PluralOne
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ReloadFailure describes a language file which could not be loaded
+type ReloadFailure struct {
+	File string
+	Err  error
+}
+
+// ReloadReport describes the outcome of reloading the language files
+type ReloadReport struct {
+	// Files which were (re)loaded successfully
+	Loaded []string
+	// Files which failed to load. The last good version of their language is kept.
+	Failed []ReloadFailure
+	// Files which were deleted, along with their language
+	Removed []string
+	// The validation results of the languages which are now active
+	Validation []*ValidationReport
+}
+
+// Changed returns true if the active languages were replaced
+func (r *ReloadReport) Changed() bool {
+	return len(r.Loaded) > 0 || len(r.Removed) > 0
+}
+
+// Reload re-parses every language file, and swaps in the new languages.
+// Files which fail to load are reported, and the last good version of their language is kept.
+func (i *I18n) Reload() (*ReloadReport, error) {
+	return i.reload(true)
+}
+
+// Watch polls the language directory for changes, and reloads any file which was modified, added, or removed.
+// This blocks forever, so should be run in its own goroutine.
+func (i *I18n) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := i.reload(false)
+
+		if err != nil {
+			log.Printf("Error watching language files: %s", err)
+			continue
+		}
+
+		if !report.Changed() {
+			continue
+		}
+
+		log.Printf("Reloaded language files - loaded %v, removed %v", report.Loaded, report.Removed)
+
+		for _, report := range report.Validation {
+			if !report.IsClean() {
+				log.Printf("Language %s has problems:\n%s", report.Language, report)
+			}
+		}
+	}
+}
+
+// reload loads the language files which have changed since the last load, or all of them if force is set
+func (i *I18n) reload(force bool) (*ReloadReport, error) {
+	i.reloading.Lock()
+	defer i.reloading.Unlock()
+
+	entries, err := os.ReadDir(i.path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.RLock()
+	current := i.Languages
+	known := i.files
+	i.mu.RUnlock()
+
+	report := &ReloadReport{}
+	next := map[string]Language{}
+	nextFiles := map[string]languageFile{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json5" {
+			continue
+		}
+
+		info, err := entry.Info()
+
+		if err != nil {
+			report.Failed = append(report.Failed, ReloadFailure{File: entry.Name(), Err: err})
+			continue
+		}
+
+		previous, wasKnown := known[entry.Name()]
+
+		if !force && wasKnown && previous.ModTime.Equal(info.ModTime()) {
+			if lang, ok := current[previous.ISO]; ok {
+				next[previous.ISO] = lang
+			}
+
+			nextFiles[entry.Name()] = previous
+			continue
+		}
+
+		lang, err := LoadLanguageFile(filepath.Join(i.path, entry.Name()))
+
+		if err != nil {
+			log.Printf("Error loading language file, keeping the last good version: %s", err)
+			report.Failed = append(report.Failed, ReloadFailure{File: entry.Name(), Err: err})
+
+			if lang, ok := current[previous.ISO]; wasKnown && ok {
+				next[previous.ISO] = lang
+			}
+
+			// Remember the new modification time, so the broken file isn't retried until it changes again.
+			// A new file which never loaded has no language yet.
+			nextFiles[entry.Name()] = languageFile{ISO: previous.ISO, ModTime: info.ModTime()}
+
+			continue
+		}
+
+		next[lang.ISO] = lang
+		nextFiles[entry.Name()] = languageFile{ISO: lang.ISO, ModTime: info.ModTime()}
+		report.Loaded = append(report.Loaded, entry.Name())
+	}
+
+	for name, file := range known {
+		if _, ok := nextFiles[name]; !ok {
+			report.Removed = append(report.Removed, fmt.Sprintf("%s (%s)", name, file.ISO))
+		}
+	}
+
+	sort.Strings(report.Removed)
+
+	if _, ok := next[DefaultLocale]; !ok {
+		if lang, ok := current[DefaultLocale]; ok {
+			// Never drop the default language, as every other language falls back to it
+			next[DefaultLocale] = lang
+		} else {
+			return nil, fmt.Errorf("the default language (%s) was not found in %s", DefaultLocale, i.path)
+		}
+	}
+
+	i.mu.Lock()
+	i.Languages = next
+	i.files = nextFiles
+	i.mu.Unlock()
+
+	report.Validation = validateLanguages(next)
+
+	return report, nil
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlaceholderMismatch describes a key whose placeholders differ from the default language
+type PlaceholderMismatch struct {
+	Key      string
+	Expected []string
+	Found    []string
+}
+
+// ValidationReport lists the problems found in a single language, compared against the default language
+type ValidationReport struct {
+	Language string
+	// Keys which exist in the default language, but not in this one
+	Missing []string
+	// Keys which exist in this language, but not in the default one
+	Extra []string
+	// Keys which use different placeholders than the default language
+	Mismatched []PlaceholderMismatch
+}
+
+// IsClean returns true if no problems were found
+func (r *ValidationReport) IsClean() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+func (r *ValidationReport) String() string {
+	var lines []string
+
+	for _, key := range r.Missing {
+		lines = append(lines, "  missing: "+key)
+	}
+
+	for _, key := range r.Extra {
+		lines = append(lines, "  extra: "+key)
+	}
+
+	for _, mismatch := range r.Mismatched {
+		lines = append(lines, fmt.Sprintf("  placeholders differ: %s (expected %v, found %v)", mismatch.Key, mismatch.Expected, mismatch.Found))
+	}
+
+	if len(lines) == 0 {
+		return "  no problems found"
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Validate compares every loaded language against the default language, and reports the differences.
+// Reports are sorted by language.
+func (i *I18n) Validate() []*ValidationReport {
+	return validateLanguages(i.snapshot())
+}
+
+func validateLanguages(languages map[string]Language) []*ValidationReport {
+	reference, ok := languages[DefaultLocale]
+
+	if !ok {
+		return nil
+	}
+
+	var reports []*ValidationReport
+
+	for _, lang := range languages {
+		reports = append(reports, ValidateLanguage(reference, lang))
+	}
+
+	sort.Slice(reports, func(a, b int) bool {
+		return reports[a].Language < reports[b].Language
+	})
+
+	return reports
+}
+
+// ValidateLanguage compares a language against a reference language
+func ValidateLanguage(reference Language, lang Language) *ValidationReport {
+	report := &ValidationReport{
+		Language: lang.ISO,
+	}
+
+	referenceKeys := keySet(reference)
+	languageKeys := keySet(lang)
+
+	for key := range referenceKeys {
+		if isMetaKey(key) {
+			continue
+		}
+
+		if !languageKeys[key] {
+			report.Missing = append(report.Missing, key)
+		}
+	}
+
+	for key := range languageKeys {
+		if isMetaKey(key) {
+			continue
+		}
+
+		if !referenceKeys[key] {
+			report.Extra = append(report.Extra, key)
+		}
+	}
+
+	for key, snippet := range lang.Bindings {
+		if expected, ok := reference.Bindings[key]; ok {
+			report.compare(key, expected.Params(), snippet.Params())
+		}
+	}
+
+	for key, list := range lang.Lists {
+		expected, ok := reference.Lists[key]
+
+		if !ok {
+			continue
+		}
+
+		// Lists (e.g. aliases) may differ in length, so compare the placeholders across all entries
+		report.compare(key, listParams(expected), listParams(list))
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	sort.Slice(report.Mismatched, func(a, b int) bool {
+		return report.Mismatched[a].Key < report.Mismatched[b].Key
+	})
+
+	return report
+}
+
+func (r *ValidationReport) compare(key string, expected []string, found []string) {
+	if strings.Join(expected, ",") == strings.Join(found, ",") {
+		return
+	}
+
+	r.Mismatched = append(r.Mismatched, PlaceholderMismatch{
+		Key:      key,
+		Expected: expected,
+		Found:    found,
+	})
+}
+
+func keySet(lang Language) map[string]bool {
+	keys := map[string]bool{}
+
+	for _, key := range lang.Keys {
+		keys[key] = true
+	}
+
+	return keys
+}
+
+func listParams(list []Snippet) []string {
+	found := map[string]bool{}
+
+	for _, snippet := range list {
+		snippet.collectParams(found)
+	}
+
+	names := make([]string, 0, len(found))
+
+	for name := range found {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// isMetaKey returns true for keys which describe the language file itself, and are expected to differ
+func isMetaKey(key string) bool {
+	return strings.HasPrefix(key, "meta.")
+}
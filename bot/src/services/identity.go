@@ -0,0 +1,272 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Users first seen on the warframe.market socket are given a shadow identity, with an ID made of this prefix and their WFM ID.
+// The shadow identity is merged into their Discord user once they link their accounts.
+const ShadowUserPrefix = "wfm:"
+
+var ErrAccountAlreadyLinked = errors.New("this warframe.market account is already linked to another Discord account")
+
+// SocketIdentity is what we know about a warframe.market user when they contact us
+type SocketIdentity struct {
+	ID       string    // Their WFM ID
+	Name     string    // Their in-game name, if known
+	Locale   string    // Their warframe.market locale, if known
+	Platform string    // The platform of their account, if known
+	LastSeen time.Time // When warframe.market last saw them, if known
+}
+
+// IsShadow returns true if the user has only been seen on the warframe.market socket, and has not linked a Discord account
+func (u *User) IsShadow() bool {
+	return strings.HasPrefix(u.ID, ShadowUserPrefix)
+}
+
+// ResolveSocketUser loads the user with the given WFM ID, creating a shadow identity for them if they are new.
+// Their last seen time is updated, along with their name and warframe.market locale when they are known.
+func (db *Database) ResolveSocketUser(identity SocketIdentity) (*User, error) {
+	user, err := db.GetUserByWFMID(identity.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if user == nil || user.ID == "" {
+		user = &User{
+			ID:        ShadowUserPrefix + identity.ID,
+			Name:      identity.Name,
+			WfmID:     sql.NullString{String: identity.ID, Valid: true},
+			FirstSeen: now,
+		}
+	}
+
+	user.LastSeen = now
+
+	if identity.Name != "" {
+		// Users with several linked accounts only mirror the name of their default one
+		if user.WfmID.String == identity.ID {
+			user.WfmUsername = sql.NullString{String: identity.Name, Valid: true}
+		}
+
+		if user.IsShadow() {
+			user.Name = identity.Name
+		}
+
+		err = db.ObserveAccountName(identity.ID, identity.Name)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Kept apart from the language the user chose, as a missing choice means "follow my Discord client"
+	if identity.Locale != "" {
+		user.WfmLocale = sql.NullString{String: identity.Locale, Valid: true}
+	}
+
+	err = db.Save(user)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// LinkWFMAccount links the warframe.market account to the Discord user, on the identity's platform.
+// Any shadow identity of the account is merged into the Discord user, moving its alerts and awards across.
+// The first account a user links becomes their default.
+func (db *Database) LinkWFMAccount(user *User, identity SocketIdentity) error {
+	return db.Inner.Transaction(func(tx *gorm.DB) error {
+		return linkWFMAccount(tx, user, identity)
+	})
+}
+
+// RedeemLinkCode uses up the code, and links the account that sent it in the same transaction.
+// If the link fails, e.g. because the account is linked to someone else, the code can still be used.
+func (db *Database) RedeemLinkCode(code *LinkCode, user *User, identity SocketIdentity) error {
+	return db.Inner.Transaction(func(tx *gorm.DB) error {
+		err := code.consume(tx, identity.ID)
+
+		if err != nil {
+			return err
+		}
+
+		return linkWFMAccount(tx, user, identity)
+	})
+}
+
+// linkWFMAccount links the account within the transaction, see LinkWFMAccount
+func linkWFMAccount(tx *gorm.DB, user *User, identity SocketIdentity) error {
+	platform := identity.Platform
+
+	if platform == "" {
+		platform = defaultPlatform
+	}
+
+	var account LinkedAccount
+
+	err := tx.Where("wfm_id = ?", identity.ID).Limit(1).Find(&account).Error
+
+	if err != nil {
+		return err
+	}
+
+	if account.ID != 0 && account.UserID != user.ID {
+		return ErrAccountAlreadyLinked
+	}
+
+	var existing LinkedAccount
+
+	err = tx.Where("user_id = ? AND platform = ?", user.ID, platform).Limit(1).Find(&existing).Error
+
+	if err != nil {
+		return err
+	}
+
+	if existing.ID != 0 && existing.WfmID != identity.ID {
+		return ErrPlatformAlreadyLinked
+	}
+
+	var owners []User
+
+	err = tx.Where("wfm_id = ? AND id <> ?", identity.ID, user.ID).Find(&owners).Error
+
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		if !owner.IsShadow() {
+			return ErrAccountAlreadyLinked
+		}
+
+		err = mergeShadowUser(tx, &owner, user)
+
+		if err != nil {
+			return fmt.Errorf("error merging %s into %s: %w", owner.ID, user.ID, err)
+		}
+	}
+
+	var defaults int64
+
+	err = tx.Model(&LinkedAccount{}).Where("user_id = ? AND is_default", user.ID).Count(&defaults).Error
+
+	if err != nil {
+		return err
+	}
+
+	if account.ID == 0 {
+		account = LinkedAccount{
+			UserID:   user.ID,
+			Platform: platform,
+			WfmID:    identity.ID,
+		}
+	}
+
+	account.Username = identity.Name
+	account.VerifiedAt = time.Now()
+	account.IsDefault = account.IsDefault || defaults == 0
+
+	if !identity.LastSeen.IsZero() {
+		account.LastSeen = sql.NullTime{Time: identity.LastSeen, Valid: true}
+	}
+
+	err = tx.Save(&account).Error
+
+	// Someone else linked the account, or the platform, between the checks above and saving it
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrAccountAlreadyLinked
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if account.IsDefault {
+		user.useAccount(&account)
+	}
+
+	if identity.Locale != "" {
+		user.WfmLocale = sql.NullString{String: identity.Locale, Valid: true}
+	}
+
+	return tx.Save(user).Error
+}
+
+// migrateWfmLocales moves the warframe.market locale of shadow identities to its own column.
+// They can't choose a language, so any locale they have was adopted from warframe.market.
+func (db *Database) migrateWfmLocales() error {
+	return db.Inner.Model(&User{}).
+		Where("id LIKE ? AND locale IS NOT NULL", ShadowUserPrefix+"%").
+		Updates(map[string]interface{}{
+			"wfm_locale": gorm.Expr("locale"),
+			"locale":     nil,
+		}).Error
+}
+
+// mergeShadowUser moves everything owned by the shadow identity to the user, and deletes the shadow identity
+func mergeShadowUser(tx *gorm.DB, shadow *User, user *User) error {
+	err := tx.Model(&Alert{}).Where("user_id = ?", shadow.ID).Update("user_id", user.ID).Error
+
+	if err != nil {
+		return err
+	}
+
+	// Badges the user already has are dropped, so nobody holds the same badge twice
+	err = tx.Unscoped().
+		Where("user_id = ? AND badge_id IN (?)", shadow.ID, tx.Model(&Award{}).Select("badge_id").Where("user_id = ?", user.ID)).
+		Delete(&Award{}).Error
+
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&Award{}).Where("user_id = ?", shadow.ID).Update("user_id", user.ID).Error
+
+	if err != nil {
+		return err
+	}
+
+	// Counters are added together, as the user may have counted the same things under both identities
+	err = tx.Exec(`INSERT INTO user_counters (user_id, name, count)
+		SELECT ?, name, count FROM user_counters WHERE user_id = ?
+		ON CONFLICT (user_id, name) DO UPDATE SET count = user_counters.count + EXCLUDED.count`, user.ID, shadow.ID).Error
+
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("user_id = ?", shadow.ID).Delete(&UserCounter{}).Error
+
+	if err != nil {
+		return err
+	}
+
+	if !user.WfmLocale.Valid && shadow.WfmLocale.Valid {
+		user.WfmLocale = shadow.WfmLocale
+	}
+
+	if !shadow.FirstSeen.IsZero() && shadow.FirstSeen.Before(user.FirstSeen) {
+		user.FirstSeen = shadow.FirstSeen
+	}
+
+	if shadow.LastSeen.After(user.LastSeen) {
+		user.LastSeen = shadow.LastSeen
+	}
+
+	user.Entitlements |= shadow.Entitlements
+
+	// Deleted rows keep their unique WFM ID, so the shadow identity must be removed for good
+	return tx.Unscoped().Delete(shadow).Error
+}
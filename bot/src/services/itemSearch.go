@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var ErrItemNotFound = errors.New("no item matches the given name")
+
+// The minimum similarity for a name to be considered a match, between 0 and 1
+const itemMatchThreshold = 0.6
+
+// How long the item names are cached before being read from the database again
+const itemIndexLifetime = time.Hour
+
+// ItemMatch is an item found by its name
+type ItemMatch struct {
+	ItemID string
+	Slug   string
+	Name   string  // The name which matched the query
+	Locale string  // The item translation locale of the name which matched
+	Score  float64 // How closely the name matched, between 0 and 1
+}
+
+// itemIndexEntry is a single translated name of an item
+type itemIndexEntry struct {
+	itemID     string
+	slug       string
+	name       string
+	locale     string
+	normalized string
+}
+
+// ItemIndex searches every translated item name, across every locale
+type ItemIndex struct {
+	mu      sync.RWMutex
+	entries []itemIndexEntry
+	names   map[string]map[string]string // Item ID -> locale -> name
+	loaded  time.Time
+}
+
+var Items = &ItemIndex{}
+
+// Invalidate drops the cached names, so they are read again on the next search
+func (x *ItemIndex) Invalidate() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.loaded = time.Time{}
+}
+
+// load reads every item translation from the database, if the cache has expired
+func (x *ItemIndex) load() error {
+	x.mu.RLock()
+	fresh := time.Since(x.loaded) < itemIndexLifetime
+	x.mu.RUnlock()
+
+	if fresh {
+		return nil
+	}
+
+	var rows []struct {
+		ItemId string
+		Slug   string
+		Locale string
+		Name   string
+	}
+
+	err := DB.Inner.Model(&ItemTranslation{}).
+		Select("item_translations.item_id, items.slug, item_translations.locale, item_translations.name").
+		Joins("JOIN items ON items.id = item_translations.item_id").
+		Where("item_translations.name <> ''").
+		Scan(&rows).Error
+
+	if err != nil {
+		return err
+	}
+
+	entries := make([]itemIndexEntry, 0, len(rows))
+	names := map[string]map[string]string{}
+
+	for _, row := range rows {
+		entries = append(entries, itemIndexEntry{
+			itemID:     row.ItemId,
+			slug:       row.Slug,
+			name:       row.Name,
+			locale:     row.Locale,
+			normalized: normalizeItemName(row.Name),
+		})
+
+		if names[row.ItemId] == nil {
+			names[row.ItemId] = map[string]string{}
+		}
+
+		names[row.ItemId][row.Locale] = row.Name
+	}
+
+	x.mu.Lock()
+	x.entries = entries
+	x.names = names
+	x.loaded = time.Now()
+	x.mu.Unlock()
+
+	return nil
+}
+
+// Find returns the item whose name in any locale best matches the query
+func (x *ItemIndex) Find(query string) (*ItemMatch, error) {
+	matches, err := x.Search(query, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrItemNotFound
+	}
+
+	return &matches[0], nil
+}
+
+// Search returns up to limit items whose names match the query, the closest match first
+func (x *ItemIndex) Search(query string, limit int) ([]ItemMatch, error) {
+	if err := x.load(); err != nil {
+		return nil, err
+	}
+
+	normalized := normalizeItemName(query)
+
+	if normalized == "" {
+		return nil, nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	best := map[string]ItemMatch{}
+
+	for _, entry := range x.entries {
+		score := itemNameSimilarity(normalized, entry.normalized)
+
+		if score < itemMatchThreshold {
+			continue
+		}
+
+		if existing, ok := best[entry.itemID]; ok && existing.Score >= score {
+			continue
+		}
+
+		best[entry.itemID] = ItemMatch{
+			ItemID: entry.itemID,
+			Slug:   entry.slug,
+			Name:   entry.name,
+			Locale: entry.locale,
+			Score:  score,
+		}
+	}
+
+	matches := make([]ItemMatch, 0, len(best))
+
+	for _, match := range best {
+		matches = append(matches, match)
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+
+		// Prefer the shorter name, e.g. "Ember Prime Set" over "Ember Prime Systems Blueprint"
+		return len(matches[a].Name) < len(matches[b].Name)
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
+
+// Name returns the name of the item in the given language, falling back to English
+func (x *ItemIndex) Name(itemID string, iso *string) string {
+	if err := x.load(); err != nil {
+		return ""
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	names := x.names[itemID]
+
+	if iso != nil {
+		if name, ok := names[ItemLocale(*iso)]; ok {
+			return name
+		}
+	}
+
+	return names["en"]
+}
+
+// ItemLocale converts a language ISO code (e.g. "zh-TW") to the locale used by the item translations (e.g. "zh-hant")
+func ItemLocale(iso string) string {
+	base := baseLanguage(iso)
+
+	if base == "zh" {
+		switch strings.ToUpper(strings.TrimPrefix(iso, base+"-")) {
+		case "TW", "HK", "MO", "HANT":
+			return "zh-hant"
+		default:
+			return "zh-hans"
+		}
+	}
+
+	return base
+}
+
+// normalizeItemName lowercases the name, and reduces anything other than letters and digits to single spaces
+func normalizeItemName(name string) string {
+	var builder strings.Builder
+	space := false
+
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && builder.Len() > 0 {
+				builder.WriteRune(' ')
+			}
+
+			builder.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+
+	return builder.String()
+}
+
+// itemNameSimilarity scores how closely the query matches the name, between 0 and 1.
+// Exact matches score highest, then names starting with the query, then names containing
+// every word of the query, and finally names within a small edit distance.
+func itemNameSimilarity(query string, name string) float64 {
+	if query == name {
+		return 1
+	}
+
+	if strings.HasPrefix(name, query) {
+		return 0.9 + 0.09*float64(len(query))/float64(len(name))
+	}
+
+	words := strings.Fields(query)
+	found := 0
+
+	for _, word := range words {
+		if strings.Contains(name, word) {
+			found++
+		}
+	}
+
+	if found == len(words) {
+		return 0.8 + 0.09*float64(len(query))/float64(len(name))
+	}
+
+	q := []rune(query)
+	n := []rune(name)
+	longest := max(len(q), len(n))
+
+	return 1 - float64(levenshtein(q, n))/float64(longest)
+}
+
+// levenshtein returns the number of single character edits needed to turn a into b
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Codes avoid characters which are easily confused, such as 0 and O, or 1 and I
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	linkCodeLength   = 8
+
+	// How long a code can be redeemed for after it is issued
+	LinkCodeLifetime = time.Minute * 15
+	// How many wrong codes a warframe.market user may send before they are locked out
+	LinkMaxFailures = 5
+	// How long a wrong code counts towards the lockout, so that mistakes days apart don't add up
+	LinkFailureWindow = time.Hour
+	// How long a warframe.market user is locked out for after too many wrong codes
+	LinkLockoutDuration = time.Minute * 15
+)
+
+var (
+	ErrLinkCodeInvalid = errors.New("the link code does not exist")
+	ErrLinkCodeExpired = errors.New("the link code has expired")
+	ErrLinkCodeUsed    = errors.New("the link code has already been used")
+)
+
+// LinkLockedError is returned while a warframe.market user is locked out for sending too many wrong codes
+type LinkLockedError struct {
+	Until time.Time
+}
+
+func (e *LinkLockedError) Error() string {
+	return "too many wrong link codes, locked out until " + e.Until.Format(time.RFC3339)
+}
+
+// IssueLinkCode creates a new random code for the Discord user, replacing any code they were issued before
+func IssueLinkCode(userID string) (*LinkCode, error) {
+	// Unused codes of the user, and long expired codes of everyone, are no longer needed
+	err := DB.Inner.Unscoped().
+		Where("(user_id = ? AND used_at IS NULL) OR expires_at < ?", userID, time.Now().Add(-24*time.Hour)).
+		Delete(&LinkCode{}).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Collisions are vanishingly rare, but retry a few times rather than failing outright
+	for attempt := 0; attempt < 3; attempt++ {
+		linkCode, err := newLinkCode(userID, time.Now())
+
+		if err != nil {
+			return nil, err
+		}
+
+		result := DB.Inner.Clauses(clause.OnConflict{DoNothing: true}).Create(linkCode)
+
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		if result.RowsAffected == 1 {
+			return linkCode, nil
+		}
+	}
+
+	return nil, errors.New("could not generate a unique link code")
+}
+
+// VerifyLinkCode checks the code sent by the warframe.market user, without using it up.
+// Wrong codes count towards the user's lockout. The code is used up by Database.RedeemLinkCode.
+func VerifyLinkCode(code string, wfmID string) (*LinkCode, error) {
+	lockout, err := getLinkLockout(wfmID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockout.check(time.Now()); err != nil {
+		return nil, err
+	}
+
+	var linkCode LinkCode
+
+	err = DB.Inner.Where("code = ?", NormalizeLinkCode(code)).First(&linkCode).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Join(ErrLinkCodeInvalid, RecordLinkFailure(wfmID))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := linkCode.check(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &linkCode, nil
+}
+
+// newLinkCode creates a random code for the Discord user, which expires LinkCodeLifetime after now
+func newLinkCode(userID string, now time.Time) (*LinkCode, error) {
+	code, err := randomLinkCode()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LinkCode{
+		Code:      code,
+		UserID:    userID,
+		ExpiresAt: now.Add(LinkCodeLifetime),
+	}, nil
+}
+
+// check returns why the code can't be redeemed at the given time, or nil if it can
+func (c *LinkCode) check(now time.Time) error {
+	if c.UsedAt.Valid {
+		return ErrLinkCodeUsed
+	}
+
+	if !now.Before(c.ExpiresAt) {
+		return ErrLinkCodeExpired
+	}
+
+	return nil
+}
+
+// consume marks the code as used by the warframe.market user within the transaction. Only the first caller succeeds.
+func (c *LinkCode) consume(tx *gorm.DB, wfmID string) error {
+	result := tx.Model(&LinkCode{}).
+		Where("id = ? AND used_at IS NULL", c.ID).
+		Updates(map[string]interface{}{
+			"used_at": time.Now(),
+			"used_by": wfmID,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrLinkCodeUsed
+	}
+
+	// A successful link clears any earlier mistakes
+	return tx.Where("wfm_id = ?", wfmID).Delete(&LinkLockout{}).Error
+}
+
+// RecordLinkFailure counts a wrong code from the warframe.market user, locking them out after too many
+func RecordLinkFailure(wfmID string) error {
+	lockout, err := getLinkLockout(wfmID)
+
+	if err != nil {
+		return err
+	}
+
+	lockout.fail(time.Now())
+
+	return DB.Save(lockout)
+}
+
+// check returns a LinkLockedError if the user is locked out at the given time
+func (l *LinkLockout) check(now time.Time) error {
+	if l.LockedUntil.Valid && l.LockedUntil.Time.After(now) {
+		return &LinkLockedError{Until: l.LockedUntil.Time}
+	}
+
+	return nil
+}
+
+// fail counts a wrong code sent at the given time, locking the user out once they reach LinkMaxFailures.
+// Failures from before an expired lockout, or older than LinkFailureWindow, are forgotten first.
+func (l *LinkLockout) fail(now time.Time) {
+	expired := l.LockedUntil.Valid && !l.LockedUntil.Time.After(now)
+	stale := l.LastFailureAt.Valid && now.Sub(l.LastFailureAt.Time) > LinkFailureWindow
+
+	if expired || stale {
+		l.Failures = 0
+		l.LockedUntil = sql.NullTime{}
+	}
+
+	l.Failures++
+	l.LastFailureAt = sql.NullTime{Time: now, Valid: true}
+
+	if l.Failures >= LinkMaxFailures {
+		l.LockedUntil = sql.NullTime{Time: now.Add(LinkLockoutDuration), Valid: true}
+	}
+}
+
+// NormalizeLinkCode uppercases the code, and removes any spaces or dashes the user may have added
+func NormalizeLinkCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+
+		return r
+	}, strings.ToUpper(code))
+}
+
+func getLinkLockout(wfmID string) (*LinkLockout, error) {
+	lockout := &LinkLockout{WfmID: wfmID}
+
+	err := DB.Inner.Where("wfm_id = ?", wfmID).Limit(1).Find(lockout).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+func randomLinkCode() (string, error) {
+	var builder strings.Builder
+	size := big.NewInt(int64(len(linkCodeAlphabet)))
+
+	for i := 0; i < linkCodeLength; i++ {
+		index, err := rand.Int(rand.Reader, size)
+
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteByte(linkCodeAlphabet[index.Int64()])
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLinkCode(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	seen := map[string]bool{}
+
+	for i := 0; i < 10000; i++ {
+		code, err := newLinkCode("user", now)
+
+		if err != nil {
+			t.Fatalf("newLinkCode returned error: %s", err)
+		}
+
+		if len(code.Code) != linkCodeLength {
+			t.Fatalf("code %q has length %d, want %d", code.Code, len(code.Code), linkCodeLength)
+		}
+
+		for _, r := range code.Code {
+			if !strings.ContainsRune(linkCodeAlphabet, r) {
+				t.Fatalf("code %q contains %q, which is not in the alphabet", code.Code, r)
+			}
+		}
+
+		if seen[code.Code] {
+			t.Fatalf("code %q was generated twice", code.Code)
+		}
+
+		seen[code.Code] = true
+
+		if code.UserID != "user" || !code.ExpiresAt.Equal(now.Add(LinkCodeLifetime)) {
+			t.Fatalf("code was issued to %q until %s, want %q until %s", code.UserID, code.ExpiresAt, "user", now.Add(LinkCodeLifetime))
+		}
+	}
+}
+
+func TestLinkCodeAlphabet(t *testing.T) {
+	for _, confusing := range "01IO" {
+		if strings.ContainsRune(linkCodeAlphabet, confusing) {
+			t.Errorf("the alphabet contains %q, which is easily confused", confusing)
+		}
+	}
+}
+
+func TestLinkCodeCheck(t *testing.T) {
+	issued := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	used := sql.NullTime{Time: issued.Add(time.Minute), Valid: true}
+
+	tests := []struct {
+		name string
+		code LinkCode
+		at   time.Time
+		want error
+	}{
+		{"fresh", LinkCode{ExpiresAt: issued.Add(LinkCodeLifetime)}, issued, nil},
+		{"just before expiry", LinkCode{ExpiresAt: issued.Add(LinkCodeLifetime)}, issued.Add(LinkCodeLifetime - time.Nanosecond), nil},
+		{"at expiry", LinkCode{ExpiresAt: issued.Add(LinkCodeLifetime)}, issued.Add(LinkCodeLifetime), ErrLinkCodeExpired},
+		{"expired", LinkCode{ExpiresAt: issued.Add(LinkCodeLifetime)}, issued.Add(time.Hour), ErrLinkCodeExpired},
+		{"used", LinkCode{ExpiresAt: issued.Add(LinkCodeLifetime), UsedAt: used}, issued.Add(2 * time.Minute), ErrLinkCodeUsed},
+		{"used and expired", LinkCode{ExpiresAt: issued.Add(LinkCodeLifetime), UsedAt: used}, issued.Add(time.Hour), ErrLinkCodeUsed},
+	}
+
+	for _, test := range tests {
+		if err := test.code.check(test.at); !errors.Is(err, test.want) {
+			t.Errorf("%s: check() = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestLinkLockout(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	lockout := &LinkLockout{WfmID: "wfm"}
+
+	for i := 1; i < LinkMaxFailures; i++ {
+		lockout.fail(now)
+
+		if int(lockout.Failures) != i {
+			t.Fatalf("after %d failures, counted %d", i, lockout.Failures)
+		}
+
+		if err := lockout.check(now); err != nil {
+			t.Fatalf("locked out after %d failures: %s", i, err)
+		}
+	}
+
+	lockout.fail(now)
+
+	var locked *LinkLockedError
+
+	if err := lockout.check(now); !errors.As(err, &locked) {
+		t.Fatalf("not locked out after %d failures: %v", LinkMaxFailures, err)
+	}
+
+	if !locked.Until.Equal(now.Add(LinkLockoutDuration)) {
+		t.Errorf("locked out until %s, want %s", locked.Until, now.Add(LinkLockoutDuration))
+	}
+
+	if err := lockout.check(now.Add(LinkLockoutDuration - time.Second)); err == nil {
+		t.Errorf("lockout ended early")
+	}
+
+	// Once the lockout expires, the user can try again with a clean slate
+	expired := now.Add(LinkLockoutDuration)
+
+	if err := lockout.check(expired); err != nil {
+		t.Fatalf("still locked out after the lockout expired: %s", err)
+	}
+
+	lockout.fail(expired)
+
+	if lockout.Failures != 1 || lockout.check(expired) != nil {
+		t.Errorf("after the lockout expired, a failure counted %d and locked = %v", lockout.Failures, lockout.check(expired))
+	}
+}
+
+func TestLinkLockoutDecay(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	lockout := &LinkLockout{WfmID: "wfm"}
+
+	// Mistakes made far apart never add up to a lockout
+	for i := 0; i < LinkMaxFailures*2; i++ {
+		lockout.fail(now.Add(time.Duration(i) * 7 * 24 * time.Hour))
+
+		if lockout.Failures != 1 {
+			t.Fatalf("failure %d counted %d, want 1", i+1, lockout.Failures)
+		}
+	}
+
+	// Mistakes within the window still do
+	lockout = &LinkLockout{WfmID: "wfm"}
+
+	for i := 0; i < LinkMaxFailures; i++ {
+		lockout.fail(now.Add(time.Duration(i) * LinkFailureWindow / LinkMaxFailures))
+	}
+
+	if lockout.check(now.Add(LinkFailureWindow)) == nil {
+		t.Errorf("not locked out after %d failures within %s", LinkMaxFailures, LinkFailureWindow)
+	}
+}
+
+func TestNormalizeLinkCode(t *testing.T) {
+	tests := map[string]string{
+		"abcd2345":    "ABCD2345",
+		"ABCD-2345":   "ABCD2345",
+		" abcd 2345 ": "ABCD2345",
+	}
+
+	for input, want := range tests {
+		if got := NormalizeLinkCode(input); got != want {
+			t.Errorf("NormalizeLinkCode(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPlatformAlreadyLinked = errors.New("the user already linked a warframe.market account on this platform")
+	ErrAccountNotLinked      = errors.New("the user has no warframe.market account linked on this platform")
+)
+
+// The platform assumed for accounts linked before the platform was recorded
+const defaultPlatform = "pc"
+
+// Called after a linked account's in-game name changes, with the name it had before
+var accountRenamedHook func(account *LinkedAccount, previous string)
+
+// SetAccountRenamedHook sets the function called after a linked account's in-game name changes
+func SetAccountRenamedHook(hook func(account *LinkedAccount, previous string)) {
+	accountRenamedHook = hook
+}
+
+// Called after warframe.market bans a linked account
+var accountBannedHook func(account *LinkedAccount)
+
+// SetAccountBannedHook sets the function called after warframe.market bans a linked account
+func SetAccountBannedHook(hook func(account *LinkedAccount)) {
+	accountBannedHook = hook
+}
+
+// GetLinkedAccounts returns every warframe.market account linked to the user, the default one first
+func (db *Database) GetLinkedAccounts(userID string) ([]LinkedAccount, error) {
+	var accounts []LinkedAccount
+
+	err := db.Inner.Where("user_id = ?", userID).Order("is_default DESC, platform").Find(&accounts).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// GetLinkedAccount returns the account the user linked on the platform, or nil if there isn't one
+func (db *Database) GetLinkedAccount(userID string, platform string) (*LinkedAccount, error) {
+	var account LinkedAccount
+
+	err := db.Inner.Where("user_id = ? AND platform = ?", userID, platform).Limit(1).Find(&account).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	if account.ID == 0 {
+		return nil, nil
+	}
+
+	return &account, nil
+}
+
+// UnlinkWFMAccount removes the account the user linked on the platform.
+// If it was their default account, their oldest remaining account becomes the default.
+func (db *Database) UnlinkWFMAccount(user *User, platform string) error {
+	return db.Inner.Transaction(func(tx *gorm.DB) error {
+		var account LinkedAccount
+
+		err := tx.Where("user_id = ? AND platform = ?", user.ID, platform).Limit(1).Find(&account).Error
+
+		if err != nil {
+			return err
+		}
+
+		if account.ID == 0 {
+			return ErrAccountNotLinked
+		}
+
+		// Deleted rows keep their unique WFM ID, so the account must be removed for good
+		err = tx.Unscoped().Delete(&account).Error
+
+		if err != nil {
+			return err
+		}
+
+		if !account.IsDefault {
+			return nil
+		}
+
+		var next LinkedAccount
+
+		err = tx.Where("user_id = ?", user.ID).Order("verified_at").Limit(1).Find(&next).Error
+
+		if err != nil {
+			return err
+		}
+
+		if next.ID == 0 {
+			user.useAccount(nil)
+			return tx.Save(user).Error
+		}
+
+		return setDefaultAccount(tx, user, &next)
+	})
+}
+
+// SetDefaultAccount makes the account the user linked on the platform their default
+func (db *Database) SetDefaultAccount(user *User, platform string) error {
+	return db.Inner.Transaction(func(tx *gorm.DB) error {
+		var account LinkedAccount
+
+		err := tx.Where("user_id = ? AND platform = ?", user.ID, platform).Limit(1).Find(&account).Error
+
+		if err != nil {
+			return err
+		}
+
+		if account.ID == 0 {
+			return ErrAccountNotLinked
+		}
+
+		return setDefaultAccount(tx, user, &account)
+	})
+}
+
+func setDefaultAccount(tx *gorm.DB, user *User, account *LinkedAccount) error {
+	err := tx.Model(&LinkedAccount{}).Where("user_id = ?", user.ID).Update("is_default", false).Error
+
+	if err != nil {
+		return err
+	}
+
+	account.IsDefault = true
+
+	err = tx.Save(account).Error
+
+	if err != nil {
+		return err
+	}
+
+	user.useAccount(account)
+
+	return tx.Save(user).Error
+}
+
+// ObserveAccountName records the in-game name seen for the warframe.market account.
+// If the account is linked and the name changed, the account and its user are updated, and the renamed hook is called.
+// Seeing the account under any name means its profile can be found again, so it is no longer stale.
+func (db *Database) ObserveAccountName(wfmID string, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	var account LinkedAccount
+
+	err := db.Inner.Where("wfm_id = ?", wfmID).Limit(1).Find(&account).Error
+
+	if err != nil {
+		return err
+	}
+
+	if account.ID == 0 || (account.Username == name && !account.Stale) {
+		return nil
+	}
+
+	previous := account.Username
+
+	err = db.Inner.Model(&account).Updates(map[string]interface{}{
+		"username": name,
+		"stale":    false,
+	}).Error
+
+	if err != nil {
+		return err
+	}
+
+	if account.IsDefault {
+		err = db.Inner.Model(&User{}).Where("id = ?", account.UserID).Update("wfm_username", name).Error
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// Names were not recorded for some accounts linked early on, so there is nothing to tell the user about
+	if previous != "" && previous != name && accountRenamedHook != nil {
+		go accountRenamedHook(&account, previous)
+	}
+
+	return nil
+}
+
+// dedupeLinkedAccounts removes every link of a warframe.market account but the first, which were possible while the
+// index on the WFM ID was missing
+func dedupeLinkedAccounts(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&LinkedAccount{}) {
+		return nil
+	}
+
+	return db.Exec(`DELETE FROM linked_accounts a USING linked_accounts b
+		WHERE a.wfm_id = b.wfm_id AND a.id > b.id`).Error
+}
+
+// useAccount mirrors the default linked account on the user, or clears it if there is none
+func (u *User) useAccount(account *LinkedAccount) {
+	if account == nil {
+		u.WfmID = sql.NullString{}
+		u.WfmUsername = sql.NullString{}
+		u.PreferredPlatform = sql.NullString{}
+		return
+	}
+
+	u.WfmID = sql.NullString{String: account.WfmID, Valid: true}
+	u.WfmUsername = sql.NullString{String: account.Username, Valid: account.Username != ""}
+	u.PreferredPlatform = sql.NullString{String: account.Platform, Valid: true}
+}
+
+// migrateLinkedAccounts creates the linked account of every Discord user who linked an account before they were recorded separately
+func (db *Database) migrateLinkedAccounts() error {
+	var users []User
+
+	err := db.Inner.
+		Where("wfm_id IS NOT NULL AND id NOT LIKE ?", ShadowUserPrefix+"%").
+		Where("id NOT IN (?)", db.Inner.Model(&LinkedAccount{}).Select("user_id")).
+		Find(&users).Error
+
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		platform := defaultPlatform
+
+		if user.PreferredPlatform.Valid && user.PreferredPlatform.String != "" {
+			platform = user.PreferredPlatform.String
+		}
+
+		err = db.Create(&LinkedAccount{
+			UserID:     user.ID,
+			Platform:   platform,
+			WfmID:      user.WfmID.String,
+			Username:   user.WfmUsername.String,
+			VerifiedAt: user.UpdatedAt,
+			IsDefault:  true,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import "sort"
+
+// MarketSummary is a snapshot of the current prices of an item
+type MarketSummary struct {
+	LowestSell *int     // The cheapest sell order from an online seller, if any
+	HighestBuy *int     // The most generous buy order from an online buyer, if any
+	Median     *float64 // The volume weighted median price of the trades in the last 48 hours, if any
+	Volume     int      // The number of items traded in the last 48 hours
+	Filtered   int      // The number of orders left out as outliers
+}
+
+// GetMarketSummary fetches the current orders and recent statistics of the item.
+// When rank is given, only orders and statistics for that mod rank are considered. Outlier orders are left out, and counted.
+func GetMarketSummary(slug string, platform string, rank *int) (*MarketSummary, error) {
+	orders, err := API.GetItemOrders(slug, platform)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statistics, err := API.GetItemStatistics(slug, platform)
+
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &MarketSummary{}
+	sides := map[string][]BookOrder{}
+
+	for _, order := range orders {
+		// Offline users can't trade, so their orders don't reflect the current price
+		if !order.Visible || order.User.Status == PresenceOffline || !matchesRank(order.ModRank, rank) {
+			continue
+		}
+
+		sides[order.OrderType] = append(sides[order.OrderType], BookOrder{
+			ID:         order.ID,
+			Rank:       order.ModRank,
+			OrderType:  order.OrderType,
+			Price:      order.Platinum,
+			Reputation: order.User.Reputation,
+		})
+	}
+
+	for orderType, side := range sides {
+		kept, filtered := FilterOutliers(side)
+		summary.Filtered += filtered
+
+		for _, order := range kept {
+			price := order.Price
+
+			switch orderType {
+			case string(OrderTypeSell):
+				if summary.LowestSell == nil || price < *summary.LowestSell {
+					summary.LowestSell = &price
+				}
+			case string(OrderTypeBuy):
+				if summary.HighestBuy == nil || price > *summary.HighestBuy {
+					summary.HighestBuy = &price
+				}
+			}
+		}
+	}
+
+	var window []ApiStatistic
+
+	for _, statistic := range statistics.FortyEightHours {
+		if matchesRank(statistic.ModRank, rank) && statistic.Volume > 0 {
+			window = append(window, statistic)
+			summary.Volume += statistic.Volume
+		}
+	}
+
+	summary.Median = weightedMedian(window, summary.Volume)
+
+	return summary, nil
+}
+
+// matchesRank returns true if no rank is wanted, or the order is for the wanted rank
+func matchesRank(actual *int, wanted *int) bool {
+	if wanted == nil {
+		return true
+	}
+
+	return actual != nil && *actual == *wanted
+}
+
+// weightedMedian returns the median of the hourly medians, weighting each hour by its volume
+func weightedMedian(statistics []ApiStatistic, volume int) *float64 {
+	if volume == 0 {
+		return nil
+	}
+
+	sort.Slice(statistics, func(a, b int) bool {
+		return statistics[a].Median < statistics[b].Median
+	})
+
+	seen := 0
+
+	for _, statistic := range statistics {
+		seen += statistic.Volume
+
+		if seen*2 >= volume {
+			median := statistic.Median
+			return &median
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,657 @@
+package services
+
+import (
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How long an order stays in the book without being seen on the socket or in a snapshot
+const OrderBookTTL = time.Hour * 2
+
+// How often a book which is being read is reconciled against warframe.market
+const OrderBookReconcileInterval = time.Minute * 10
+
+// Books which haven't been read for this long are only fed from the socket, to spare the API
+const orderBookIdle = time.Hour
+
+// The rank of orders for items which can't be ranked
+const unranked = -1
+
+// BookOrder is a visible order held in the order book
+type BookOrder struct {
+	ID         string
+	ItemID     string
+	Slug       string
+	Platform   string
+	Rank       *int // Only set for items which can be ranked
+	OrderType  string
+	Price      int
+	Quantity   int
+	UserID     string
+	UserName   string
+	UserStatus string // Can be "online", "ingame", "invisible" or "offline"
+	Reputation int
+	SeenAt     time.Time // When the order was last seen on the socket or in a snapshot
+}
+
+// DepthLevel is every order at a single price
+type DepthLevel struct {
+	Price    int
+	Quantity int
+	Orders   int
+}
+
+// bookKey identifies the book of an item on a platform
+type bookKey struct {
+	itemID   string
+	platform string
+}
+
+// orderLocation is where an order is held, as updates and removals don't always say
+type orderLocation struct {
+	key  bookKey
+	rank int
+}
+
+// itemBook holds the orders of an item on a platform, by rank
+type itemBook struct {
+	slug         string
+	ranks        map[int]map[string]*BookOrder
+	reconciledAt time.Time // When the book was last replaced by a snapshot
+	readAt       time.Time // When the book was last read by a command
+}
+
+// bookEvent is an order put in or removed from the book by the socket, while a snapshot was being fetched
+type bookEvent struct {
+	order   *BookOrder // The order put in the book, or nil if it was removed
+	removed string
+}
+
+// bookRecording holds the socket events of a book while its snapshot is being fetched
+type bookRecording struct {
+	events []bookEvent
+}
+
+// OrderBook keeps the visible orders of every item, fed from the socket and reconciled against REST snapshots
+type OrderBook struct {
+	mu         sync.RWMutex
+	books      map[bookKey]*itemBook
+	orders     map[string]orderLocation
+	recordings map[bookKey][]*bookRecording
+	listeners  []func(itemID string, platform string)
+
+	// The books changed since the listeners were last told, so a burst of changes to one item is only told once
+	pendingMu sync.Mutex
+	pending   map[bookKey]bool
+	wake      chan struct{}
+}
+
+var Orders = NewOrderBook()
+
+func NewOrderBook() *OrderBook {
+	book := &OrderBook{
+		books:      map[bookKey]*itemBook{},
+		orders:     map[string]orderLocation{},
+		recordings: map[bookKey][]*bookRecording{},
+		pending:    map[bookKey]bool{},
+		wake:       make(chan struct{}, 1),
+	}
+
+	go book.notify()
+
+	return book
+}
+
+// Attach feeds the book from the new, updated and removed orders sent by the socket.
+// They share a subscription, so an order is never removed before it is added.
+func (b *OrderBook) Attach(d *SocketDispatcher) {
+	subscription := d.Subscription()
+
+	Handle(subscription, EventNewOrder, func(order *SubscriptionsNewWrappedOrder) error {
+		b.Put(&order.Order)
+		return nil
+	})
+
+	Handle(subscription, EventOrderUpdated, func(order *SubscriptionsNewWrappedOrder) error {
+		b.Put(&order.Order)
+		return nil
+	})
+
+	Handle(subscription, EventOrderRemoved, func(order *SubscriptionsRemovedOrder) error {
+		b.Remove(order.ID)
+		return nil
+	})
+}
+
+// OnChange subscribes the listener to every change of the orders of an item.
+// Listeners are called one at a time in the background, so they never hold up the socket.
+func (b *OrderBook) OnChange(listener func(itemID string, platform string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.listeners = append(b.listeners, listener)
+}
+
+// Put adds or replaces the order sent by the socket, removing it once it is hidden
+func (b *OrderBook) Put(order *SubscriptionsNewOrder) {
+	if !order.Visible {
+		b.Remove(order.ID)
+		return
+	}
+
+	b.mu.Lock()
+
+	key := bookKey{itemID: order.Item.ID, platform: order.Platform}
+	book := b.book(key)
+	book.slug = order.Item.Slug
+
+	b.record(key, bookEvent{order: &BookOrder{
+		ID:         order.ID,
+		ItemID:     order.Item.ID,
+		Slug:       order.Item.Slug,
+		Platform:   order.Platform,
+		Rank:       orderRank(order),
+		OrderType:  order.OrderType,
+		Price:      order.Price,
+		Quantity:   order.Quantity,
+		UserID:     order.User.ID,
+		UserName:   order.User.GameName,
+		UserStatus: order.User.Status,
+		Reputation: order.User.Reputation,
+		SeenAt:     time.Now(),
+	}})
+
+	b.mu.Unlock()
+
+	// Only once the order is in the book, so in-game alerts for the seller can find it
+	Presences.Observe(order.User.ID, Presence{
+		Status:   order.User.Status,
+		LastSeen: order.User.LastSeen,
+		Name:     order.User.GameName,
+		Locale:   order.User.Locale,
+	})
+
+	b.changed(key)
+}
+
+// Remove drops the order from the book, if it is held
+func (b *OrderBook) Remove(orderID string) {
+	b.mu.Lock()
+
+	location, ok := b.orders[orderID]
+
+	b.record(location.key, bookEvent{removed: orderID})
+
+	b.mu.Unlock()
+
+	if ok {
+		b.changed(location.key)
+	}
+}
+
+// Reconcile replaces the orders of the item with a snapshot from warframe.market.
+// The socket keeps feeding the book while the snapshot is fetched, and what it sent is replayed over the snapshot,
+// as it is newer. An order removed in the meantime stays removed, even if it is still in the snapshot.
+func (b *OrderBook) Reconcile(itemID string, slug string, platform string) error {
+	key := bookKey{itemID: itemID, platform: platform}
+	recording := &bookRecording{}
+
+	b.mu.Lock()
+	b.recordings[key] = append(b.recordings[key], recording)
+	b.mu.Unlock()
+
+	orders, err := API.GetItemOrders(slug, platform)
+
+	b.mu.Lock()
+	b.stopRecording(key, recording)
+
+	if err != nil {
+		b.mu.Unlock()
+		return err
+	}
+
+	book := b.book(key)
+	book.slug = slug
+
+	for _, orders := range book.ranks {
+		for id := range orders {
+			b.remove(id)
+		}
+	}
+
+	now := time.Now()
+
+	for _, order := range orders {
+		if !order.Visible {
+			continue
+		}
+
+		b.put(key, &BookOrder{
+			ID:         order.ID,
+			ItemID:     itemID,
+			Slug:       slug,
+			Platform:   platform,
+			Rank:       order.ModRank,
+			OrderType:  order.OrderType,
+			Price:      order.Platinum,
+			Quantity:   order.Quantity,
+			UserID:     order.User.ID,
+			UserName:   order.User.IngameName,
+			UserStatus: order.User.Status,
+			Reputation: order.User.Reputation,
+			SeenAt:     now,
+		})
+	}
+
+	for _, event := range recording.events {
+		b.apply(key, event)
+	}
+
+	book.reconciledAt = now
+
+	b.mu.Unlock()
+
+	for _, order := range orders {
+		Presences.Observe(order.User.ID, Presence{Status: order.User.Status, LastSeen: order.User.LastSeen, Name: order.User.IngameName})
+	}
+
+	b.changed(key)
+
+	return nil
+}
+
+// Ensure marks the book of the item as being read, and reconciles it if it is missing or stale
+func (b *OrderBook) Ensure(itemID string, slug string, platform string) error {
+	key := bookKey{itemID: itemID, platform: platform}
+
+	b.mu.Lock()
+	b.book(key).readAt = time.Now()
+	b.mu.Unlock()
+
+	return b.Refresh(itemID, slug, platform, OrderBookReconcileInterval)
+}
+
+// Refresh reconciles the book of the item if its last snapshot is older than maxAge, without marking it as being read
+func (b *OrderBook) Refresh(itemID string, slug string, platform string, maxAge time.Duration) error {
+	if time.Since(b.ReconciledAt(itemID, platform)) <= maxAge {
+		return nil
+	}
+
+	return b.Reconcile(itemID, slug, platform)
+}
+
+// ReconciledAt returns when the book of the item was last replaced by a snapshot, or the zero time if never
+func (b *OrderBook) ReconciledAt(itemID string, platform string) time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	book, ok := b.books[bookKey{itemID: itemID, platform: platform}]
+
+	if !ok {
+		return time.Time{}
+	}
+
+	return book.reconciledAt
+}
+
+// BestAsk returns the cheapest sell order from a seller who isn't offline, ignoring outliers, or nil if there is none.
+// When rank is given, only orders for that rank are considered.
+func (b *OrderBook) BestAsk(itemID string, platform string, rank *int) *BookOrder {
+	return b.best(itemID, platform, rank, string(OrderTypeSell))
+}
+
+// BestBid returns the most generous buy order from a buyer who isn't offline, ignoring outliers, or nil if there is none.
+// When rank is given, only orders for that rank are considered.
+func (b *OrderBook) BestBid(itemID string, platform string, rank *int) *BookOrder {
+	return b.best(itemID, platform, rank, string(OrderTypeBuy))
+}
+
+// Depth returns up to levels prices of the orders from users who aren't offline, ignoring outliers, the best price first
+func (b *OrderBook) Depth(itemID string, platform string, rank *int, orderType string, levels int) []DepthLevel {
+	byPrice := map[int]*DepthLevel{}
+
+	orders, _ := b.Clean(itemID, platform, rank, orderType)
+
+	for _, order := range orders {
+		level, ok := byPrice[order.Price]
+
+		if !ok {
+			level = &DepthLevel{Price: order.Price}
+			byPrice[order.Price] = level
+		}
+
+		level.Quantity += order.Quantity
+		level.Orders++
+	}
+
+	depth := make([]DepthLevel, 0, len(byPrice))
+
+	for _, level := range byPrice {
+		depth = append(depth, *level)
+	}
+
+	sort.Slice(depth, func(a, c int) bool {
+		if orderType == string(OrderTypeBuy) {
+			return depth[a].Price > depth[c].Price
+		}
+
+		return depth[a].Price < depth[c].Price
+	})
+
+	if levels > 0 && len(depth) > levels {
+		depth = depth[:levels]
+	}
+
+	return depth
+}
+
+// Active returns copies of the orders of the given type from users who aren't offline, the best price first
+func (b *OrderBook) Active(itemID string, platform string, rank *int, orderType string) []BookOrder {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	book, ok := b.books[bookKey{itemID: itemID, platform: platform}]
+
+	if !ok {
+		return nil
+	}
+
+	var active []BookOrder
+
+	for bookRank, orders := range book.ranks {
+		if rank != nil && bookRank != *rank {
+			continue
+		}
+
+		for _, order := range orders {
+			if order.OrderType != orderType {
+				continue
+			}
+
+			copied := *order
+
+			// The presence index hears about users more often than their orders are updated
+			if status := Presences.Status(order.UserID); status != "" {
+				copied.UserStatus = status
+			}
+
+			// Offline users can't trade, so their orders don't reflect the current price
+			if copied.UserStatus == PresenceOffline {
+				continue
+			}
+
+			active = append(active, copied)
+		}
+	}
+
+	sort.SliceStable(active, func(a, c int) bool {
+		if orderType == string(OrderTypeBuy) {
+			return active[a].Price > active[c].Price
+		}
+
+		return active[a].Price < active[c].Price
+	})
+
+	return active
+}
+
+// Clean returns the active orders which aren't outliers, the best price first, and how many outliers were left out
+func (b *OrderBook) Clean(itemID string, platform string, rank *int, orderType string) ([]BookOrder, int) {
+	return FilterOutliers(b.Active(itemID, platform, rank, orderType))
+}
+
+// Sellers returns the sell orders of the item, the sellers most likely to trade right now first, then the cheapest,
+// then the most reputable. When onlineOnly is true, only sellers who are online or in game are returned. Outliers are left out.
+func (b *OrderBook) Sellers(itemID string, platform string, rank *int, onlineOnly bool) []BookOrder {
+	var sellers []BookOrder
+
+	orders, _ := b.Clean(itemID, platform, rank, string(OrderTypeSell))
+
+	for _, order := range orders {
+		if !onlineOnly || IsOnline(order.UserStatus) {
+			sellers = append(sellers, order)
+		}
+	}
+
+	sort.SliceStable(sellers, func(a, c int) bool {
+		if rankA, rankC := PresenceRank(sellers[a].UserStatus), PresenceRank(sellers[c].UserStatus); rankA != rankC {
+			return rankA < rankC
+		}
+
+		if sellers[a].Price != sellers[c].Price {
+			return sellers[a].Price < sellers[c].Price
+		}
+
+		return sellers[a].Reputation > sellers[c].Reputation
+	})
+
+	return sellers
+}
+
+// BySeller returns copies of every sell order of the user, across every item
+func (b *OrderBook) BySeller(userID string) []BookOrder {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var orders []BookOrder
+
+	for _, book := range b.books {
+		for _, rank := range book.ranks {
+			for _, order := range rank {
+				if order.UserID == userID && order.OrderType == string(OrderTypeSell) {
+					orders = append(orders, *order)
+				}
+			}
+		}
+	}
+
+	return orders
+}
+
+// Evict drops the orders which haven't been seen for longer than the TTL, and the empty books nobody reads
+func (b *OrderBook) Evict() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	for key, book := range b.books {
+		empty := true
+
+		for _, orders := range book.ranks {
+			for id, order := range orders {
+				if now.Sub(order.SeenAt) > OrderBookTTL {
+					b.remove(id)
+				}
+			}
+
+			if len(orders) > 0 {
+				empty = false
+			}
+		}
+
+		if empty && now.Sub(book.readAt) > orderBookIdle {
+			delete(b.books, key)
+		}
+	}
+}
+
+// Maintain evicts stale orders and reconciles the books being read, forever
+func (b *OrderBook) Maintain(interval time.Duration) {
+	for {
+		b.Evict()
+
+		for key, slug := range b.due() {
+			err := b.Reconcile(key.itemID, slug, key.platform)
+
+			if err != nil {
+				log.Printf("Error reconciling the order book of %s on %s: %s", slug, key.platform, err)
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// due returns the slugs of the books read recently, whose last snapshot is older than the reconcile interval
+func (b *OrderBook) due() map[bookKey]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	now := time.Now()
+	due := map[bookKey]string{}
+
+	for key, book := range b.books {
+		if book.slug != "" && now.Sub(book.readAt) < orderBookIdle && now.Sub(book.reconciledAt) > OrderBookReconcileInterval {
+			due[key] = book.slug
+		}
+	}
+
+	return due
+}
+
+// best returns a copy of the best order of the given type, or nil if there is none
+func (b *OrderBook) best(itemID string, platform string, rank *int, orderType string) *BookOrder {
+	active, _ := b.Clean(itemID, platform, rank, orderType)
+
+	if len(active) == 0 {
+		return nil
+	}
+
+	return &active[0]
+}
+
+// book returns the book of the item, creating it if needed. The caller must hold the write lock.
+func (b *OrderBook) book(key bookKey) *itemBook {
+	book, ok := b.books[key]
+
+	if !ok {
+		book = &itemBook{ranks: map[int]map[string]*BookOrder{}}
+		b.books[key] = book
+	}
+
+	return book
+}
+
+// put stores the order, moving it if its rank changed. The caller must hold the write lock.
+func (b *OrderBook) put(key bookKey, order *BookOrder) {
+	b.remove(order.ID)
+
+	rank := bookRank(order.Rank)
+	book := b.book(key)
+
+	if book.ranks[rank] == nil {
+		book.ranks[rank] = map[string]*BookOrder{}
+	}
+
+	book.ranks[rank][order.ID] = order
+	b.orders[order.ID] = orderLocation{key: key, rank: rank}
+}
+
+// remove drops the order, if it is held. The caller must hold the write lock.
+func (b *OrderBook) remove(orderID string) {
+	location, ok := b.orders[orderID]
+
+	if !ok {
+		return
+	}
+
+	delete(b.orders, orderID)
+
+	if book, ok := b.books[location.key]; ok {
+		delete(book.ranks[location.rank], orderID)
+
+		if len(book.ranks[location.rank]) == 0 {
+			delete(book.ranks, location.rank)
+		}
+	}
+}
+
+// record applies the socket event to the book, and keeps it for every snapshot of the book being fetched.
+// Removals are kept by every recording, as the book of an order which isn't held yet is unknown. The caller must hold the write lock.
+func (b *OrderBook) record(key bookKey, event bookEvent) {
+	b.apply(key, event)
+
+	for recordedKey, recordings := range b.recordings {
+		if event.order != nil && recordedKey != key {
+			continue
+		}
+
+		for _, recording := range recordings {
+			recording.events = append(recording.events, event)
+		}
+	}
+}
+
+// apply puts or removes the order of the event. The caller must hold the write lock.
+func (b *OrderBook) apply(key bookKey, event bookEvent) {
+	if event.order != nil {
+		b.put(key, event.order)
+	} else {
+		b.remove(event.removed)
+	}
+}
+
+// stopRecording forgets the recording once its snapshot was fetched. The caller must hold the write lock.
+func (b *OrderBook) stopRecording(key bookKey, recording *bookRecording) {
+	recordings := b.recordings[key]
+
+	for i, candidate := range recordings {
+		if candidate == recording {
+			recordings = append(recordings[:i], recordings[i+1:]...)
+			break
+		}
+	}
+
+	if len(recordings) == 0 {
+		delete(b.recordings, key)
+	} else {
+		b.recordings[key] = recordings
+	}
+}
+
+// changed queues telling every listener the orders of the item changed
+func (b *OrderBook) changed(key bookKey) {
+	b.pendingMu.Lock()
+	b.pending[key] = true
+	b.pendingMu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// notify tells the listeners about every changed book, until the program exits
+func (b *OrderBook) notify() {
+	for range b.wake {
+		b.pendingMu.Lock()
+		pending := b.pending
+		b.pending = map[bookKey]bool{}
+		b.pendingMu.Unlock()
+
+		b.mu.RLock()
+		listeners := b.listeners
+		b.mu.RUnlock()
+
+		for key := range pending {
+			for _, listener := range listeners {
+				b.tell(listener, key)
+			}
+		}
+	}
+}
+
+// tell calls a single listener, isolating any panic to that listener
+func (b *OrderBook) tell(listener func(itemID string, platform string), key bookKey) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Order book listener for %s panicked: %v\n%s", key.itemID, r, debug.Stack())
+		}
+	}()
+
+	listener(key.itemID, key.platform)
+}
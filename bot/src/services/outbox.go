@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/teris-io/shortid"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending = OutboxStatus("pending") // Waiting to be written to the socket
+	OutboxStatusSent    = OutboxStatus("sent")    // Written to the socket, waiting for warframe.market to confirm it
+	OutboxStatusAcked   = OutboxStatus("acked")   // Confirmed as delivered by warframe.market
+	OutboxStatusFailed  = OutboxStatus("failed")  // Gave up after too many attempts
+)
+
+const (
+	// The minimum time between two messages, to stay under the warframe.market rate limits
+	OutboxInterval = time.Millisecond * 1500
+	// How long to wait for warframe.market to confirm a message, before sending it again
+	OutboxAckTimeout = time.Second * 10
+	// How many times a message is sent before it is marked as failed
+	OutboxMaxAttempts = 3
+)
+
+var ErrOutboxFailed = errors.New("the message could not be delivered")
+
+// outboxResult is passed to the callers waiting on a message once it is acked or failed
+type outboxResult struct {
+	ack *MessageAcknowledgement
+	err error
+}
+
+// Outbox persists every outgoing private message, and delivers them one at a time.
+// Messages which are not confirmed in time, or were in flight when the socket dropped, are sent again.
+type Outbox struct {
+	client *SocketClient
+	wake   chan struct{}
+
+	// Guards the maps below
+	mu sync.Mutex
+	// The outbox message ID for the TempID of every message in flight
+	inflight map[string]uint
+	// The callers waiting on each outbox message
+	waiters map[uint][]chan outboxResult
+}
+
+func NewOutbox(client *SocketClient) *Outbox {
+	outbox := &Outbox{
+		client:   client,
+		wake:     make(chan struct{}, 1),
+		inflight: map[string]uint{},
+		waiters:  map[uint][]chan outboxResult{},
+	}
+
+	On(client.Events, EventMessageSent, func(ack *MessageAcknowledgement) error {
+		return outbox.acknowledge(ack)
+	})
+
+	return outbox
+}
+
+// Send stores the message in the outbox, and waits until it is confirmed by warframe.market.
+// If the context ends first, the message is still delivered, but the caller stops waiting for it.
+func (o *Outbox) Send(ctx context.Context, chatID string, message string) (*MessageAcknowledgement, error) {
+	result := make(chan outboxResult, 1)
+
+	entry, err := o.store(chatID, message, result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-result:
+		return r.ack, r.err
+	case <-ctx.Done():
+		o.mu.Lock()
+		delete(o.waiters, entry.ID)
+		o.mu.Unlock()
+
+		return nil, ctx.Err()
+	}
+}
+
+// Queue stores the message in the outbox, and returns without waiting for it to be delivered.
+// The outbox retries it like any other message.
+func (o *Outbox) Queue(chatID string, message string) error {
+	_, err := o.store(chatID, message, nil)
+	return err
+}
+
+// store persists the message and wakes the outbox up, registering result to hear how its delivery ends if given
+func (o *Outbox) store(chatID string, message string, result chan outboxResult) (*OutboxMessage, error) {
+	entry := OutboxMessage{
+		ChatID:  chatID,
+		Message: message,
+		Status:  string(OutboxStatusPending),
+	}
+
+	err := DB.Create(&entry)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result != nil {
+		o.mu.Lock()
+		o.waiters[entry.ID] = append(o.waiters[entry.ID], result)
+		o.mu.Unlock()
+	}
+
+	o.notify()
+
+	return &entry, nil
+}
+
+// Run delivers the messages in the outbox forever, so should be run in its own goroutine
+func (o *Outbox) Run() {
+	for {
+		select {
+		case <-o.wake:
+		case <-time.After(OutboxInterval):
+		}
+
+		for o.client.Connected() {
+			delivered, err := o.deliverNext()
+
+			if err != nil {
+				log.Printf("Error delivering outbox message: %s", err)
+			}
+
+			if !delivered {
+				break
+			}
+
+			// Space out the messages to respect the rate limit
+			time.Sleep(OutboxInterval)
+		}
+	}
+}
+
+// Requeue marks every message in flight as pending, so it is sent again.
+// This is called whenever the socket (re)connects, as any message in flight may have been lost.
+func (o *Outbox) Requeue() error {
+	o.mu.Lock()
+	o.inflight = map[string]uint{}
+	o.mu.Unlock()
+
+	err := DB.Inner.Model(&OutboxMessage{}).
+		Where("status = ?", OutboxStatusSent).
+		Update("status", OutboxStatusPending).Error
+
+	o.notify()
+
+	return err
+}
+
+// notify wakes the delivery loop without blocking
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverNext sends the oldest message which is pending, or was not confirmed in time.
+// Returns false if there was nothing to send.
+func (o *Outbox) deliverNext() (bool, error) {
+	var entry OutboxMessage
+
+	err := DB.Inner.
+		Where("status = ?", OutboxStatusPending).
+		Or("status = ? AND sent_at < ?", OutboxStatusSent, time.Now().Add(-OutboxAckTimeout)).
+		Order("id").
+		Limit(1).
+		Find(&entry).Error
+
+	if err != nil {
+		return false, err
+	}
+
+	if entry.ID == 0 {
+		return false, nil
+	}
+
+	o.mu.Lock()
+	if entry.TempID.Valid {
+		// Any confirmation for the previous attempt is ignored from now on
+		delete(o.inflight, entry.TempID.String)
+	}
+	o.mu.Unlock()
+
+	if entry.Attempts >= OutboxMaxAttempts {
+		entry.Status = string(OutboxStatusFailed)
+
+		if err := DB.Save(&entry); err != nil {
+			return true, err
+		}
+
+		o.resolve(entry.ID, outboxResult{err: fmt.Errorf("%w after %d attempts: %s", ErrOutboxFailed, entry.Attempts, entry.LastError.String)})
+		return true, nil
+	}
+
+	tempID, err := shortid.Generate()
+
+	if err != nil {
+		return false, err
+	}
+
+	entry.TempID = sql.NullString{String: tempID, Valid: true}
+	entry.Status = string(OutboxStatusSent)
+	entry.Attempts++
+	entry.SentAt = sql.NullTime{Time: time.Now(), Valid: true}
+	entry.LastError = sql.NullString{String: "no confirmation received", Valid: true}
+
+	if err := DB.Save(&entry); err != nil {
+		return false, err
+	}
+
+	o.mu.Lock()
+	o.inflight[tempID] = entry.ID
+	o.mu.Unlock()
+
+	log.Printf("sending pm to channel %s (attempt %d)", entry.ChatID, entry.Attempts)
+
+	err = o.client.write(SocketMessage[*SendMessage]{
+		Type: "@WS/chats/SEND_MESSAGE",
+		Data: &SendMessage{
+			ChatID:  entry.ChatID,
+			Message: entry.Message,
+			TempID:  tempID,
+		},
+	})
+
+	if err != nil {
+		o.mu.Lock()
+		delete(o.inflight, tempID)
+		o.mu.Unlock()
+
+		entry.Status = string(OutboxStatusPending)
+		entry.LastError = sql.NullString{String: err.Error(), Valid: true}
+
+		return true, errors.Join(err, DB.Save(&entry))
+	}
+
+	return true, nil
+}
+
+// acknowledge marks the message confirmed by warframe.market as acked
+func (o *Outbox) acknowledge(ack *MessageAcknowledgement) error {
+	o.mu.Lock()
+	id, ok := o.inflight[ack.TempID]
+	delete(o.inflight, ack.TempID)
+	o.mu.Unlock()
+
+	if !ok {
+		// A confirmation for an attempt which already timed out, or a message sent elsewhere
+		return nil
+	}
+
+	err := DB.Inner.Model(&OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": OutboxStatusAcked, "last_error": nil}).Error
+
+	ack.Success = true
+	o.resolve(id, outboxResult{ack: ack})
+
+	return err
+}
+
+// resolve passes the result to every caller waiting on the message
+func (o *Outbox) resolve(id uint, result outboxResult) {
+	o.mu.Lock()
+	waiters := o.waiters[id]
+	delete(o.waiters, id)
+	o.mu.Unlock()
+
+	for _, waiter := range waiters {
+		waiter <- result
+	}
+}
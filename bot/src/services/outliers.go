@@ -0,0 +1,228 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Prices further than this many scaled median absolute deviations from the median are outliers
+const outlierMADs = 3.5
+
+// When most prices are equal the deviation is zero, so quartiles are used instead, with this many ranges of slack
+const outlierIQRs = 1.5
+
+// Scales the median absolute deviation to match a standard deviation for normally distributed prices
+const madScale = 1.4826
+
+// Fewer prices than this can't tell an outlier from a thin market
+const outlierMinSamples = 5
+
+// Orders from users with less reputation are left out of stats and alerts, as bait comes from fresh accounts
+const MinOrderReputation = 1
+
+// How far back stored orders are used to judge a new one
+const OutlierWindow = time.Hour * 48
+
+// How long the bounds of an item are cached before being read from the stored orders again
+const outlierBoundsLifetime = time.Minute * 10
+
+// Why an order is left out of stats and alerts, or an empty string if it isn't
+type OutlierReason string
+
+const (
+	OutlierNone       = OutlierReason("")
+	OutlierReputation = OutlierReason("reputation") // The user doesn't have enough reputation
+	OutlierLow        = OutlierReason("low")        // The price is far below the rest, e.g. 1 platinum bait
+	OutlierHigh       = OutlierReason("high")       // The price is far above the rest, e.g. 99999 platinum trolls
+)
+
+// PriceBounds are the prices an order may have without being an outlier
+type PriceBounds struct {
+	Lower float64
+	Upper float64
+	Valid bool // Unset when there were too few prices to tell
+}
+
+// NewPriceBounds works out the bounds from the median absolute deviation of the prices, or their quartiles
+func NewPriceBounds(prices []float64) PriceBounds {
+	if len(prices) < outlierMinSamples {
+		return PriceBounds{}
+	}
+
+	sorted := append([]float64(nil), prices...)
+	sort.Float64s(sorted)
+
+	median := quantile(sorted, 0.5)
+	deviations := make([]float64, len(sorted))
+
+	for i, price := range sorted {
+		deviations[i] = math.Abs(price - median)
+	}
+
+	sort.Float64s(deviations)
+
+	if mad := quantile(deviations, 0.5); mad > 0 {
+		spread := outlierMADs * madScale * mad
+
+		return PriceBounds{Lower: median - spread, Upper: median + spread, Valid: true}
+	}
+
+	lower := quantile(sorted, 0.25)
+	upper := quantile(sorted, 0.75)
+	spread := outlierIQRs * (upper - lower)
+
+	// Every price in the middle half is the same, so allow a little room either side of it
+	if spread == 0 {
+		spread = median / 2
+	}
+
+	return PriceBounds{Lower: lower - spread, Upper: upper + spread, Valid: true}
+}
+
+// Classify returns why an order with the price, from a user with the reputation, is an outlier
+func (b PriceBounds) Classify(price int, reputation int) OutlierReason {
+	if reputation < MinOrderReputation {
+		return OutlierReputation
+	}
+
+	if !b.Valid {
+		return OutlierNone
+	}
+
+	if float64(price) < b.Lower {
+		return OutlierLow
+	}
+
+	if float64(price) > b.Upper {
+		return OutlierHigh
+	}
+
+	return OutlierNone
+}
+
+// FilterOutliers returns the orders which aren't outliers, in the same order, and how many were left out.
+// Ranks are judged separately, as a maxed mod is worth far more than an unranked one.
+func FilterOutliers(orders []BookOrder) ([]BookOrder, int) {
+	prices := map[int][]float64{}
+
+	for _, order := range orders {
+		if order.Reputation >= MinOrderReputation {
+			prices[bookRank(order.Rank)] = append(prices[bookRank(order.Rank)], float64(order.Price))
+		}
+	}
+
+	bounds := map[int]PriceBounds{}
+
+	for rank, rankPrices := range prices {
+		bounds[rank] = NewPriceBounds(rankPrices)
+	}
+
+	kept := make([]BookOrder, 0, len(orders))
+
+	for _, order := range orders {
+		if bounds[bookRank(order.Rank)].Classify(order.Price, order.Reputation) == OutlierNone {
+			kept = append(kept, order)
+		}
+	}
+
+	return kept, len(orders) - len(kept)
+}
+
+// bookRank returns the rank the order is held under
+func bookRank(rank *int) int {
+	if rank == nil {
+		return unranked
+	}
+
+	return *rank
+}
+
+// quantile returns the value at q of the sorted values, interpolating between neighbours
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	below := int(math.Floor(position))
+	above := int(math.Ceil(position))
+
+	return sorted[below] + (sorted[above]-sorted[below])*(position-float64(below))
+}
+
+// storedBoundsKey identifies the stored orders of one side of an item, at one rank
+type storedBoundsKey struct {
+	itemID string
+	sell   bool
+	rank   int
+}
+
+// storedBounds are the bounds of an item worked out from its stored orders
+type storedBounds struct {
+	bounds    PriceBounds
+	updatedAt time.Time
+}
+
+var (
+	storedBoundsMu    sync.Mutex
+	storedBoundsCache = map[storedBoundsKey]storedBounds{}
+)
+
+// StoredBounds returns the bounds of one side of the item, from every order stored in the outlier window.
+// Outliers are counted too, otherwise the bounds could never follow a price that moves past them.
+// When rank is given, only orders for that rank are used.
+func (db *Database) StoredBounds(itemID string, sell bool, rank *int) (PriceBounds, error) {
+	key := storedBoundsKey{itemID: itemID, sell: sell, rank: bookRank(rank)}
+
+	storedBoundsMu.Lock()
+	cached, ok := storedBoundsCache[key]
+	storedBoundsMu.Unlock()
+
+	if ok && time.Since(cached.updatedAt) < outlierBoundsLifetime {
+		return cached.bounds, nil
+	}
+
+	var prices []float64
+
+	query := db.Inner.Model(&TradeInfo{}).
+		Where("item_id = ? AND is_sell_order = ? AND time > ?", itemID, sell, time.Now().Add(-OutlierWindow))
+
+	if rank != nil {
+		query = query.Where("mod_rank = ?", *rank)
+	} else {
+		query = query.Where("mod_rank IS NULL")
+	}
+
+	err := query.Pluck("price", &prices).Error
+
+	if err != nil {
+		return PriceBounds{}, err
+	}
+
+	bounds := NewPriceBounds(prices)
+
+	storedBoundsMu.Lock()
+	storedBoundsCache[key] = storedBounds{bounds: bounds, updatedAt: time.Now()}
+	storedBoundsMu.Unlock()
+
+	return bounds, nil
+}
+
+// ClassifyOrder judges the new order from the socket against the orders stored for its item, at the same rank
+func (db *Database) ClassifyOrder(order *SubscriptionsNewOrder) (OutlierReason, error) {
+	bounds, err := db.StoredBounds(order.Item.ID, order.OrderType == string(OrderTypeSell), orderRank(order))
+
+	if err != nil {
+		return OutlierNone, err
+	}
+
+	return bounds.Classify(order.Price, order.User.Reputation), nil
+}
+
+// orderRank returns the rank of the order from the socket, or nil if its item can't be ranked
+func orderRank(order *SubscriptionsNewOrder) *int {
+	if order.Item.MaxModRank == 0 {
+		return nil
+	}
+
+	rank := order.ModRank
+	return &rank
+}
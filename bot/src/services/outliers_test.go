@@ -0,0 +1,143 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestQuantile(t *testing.T) {
+	tests := []struct {
+		sorted []float64
+		q      float64
+		want   float64
+	}{
+		{sorted: []float64{7}, q: 0.5, want: 7},
+		{sorted: []float64{1, 2, 3, 4}, q: 0, want: 1},
+		{sorted: []float64{1, 2, 3, 4}, q: 1, want: 4},
+		{sorted: []float64{1, 2, 3, 4}, q: 0.5, want: 2.5},
+		{sorted: []float64{1, 2, 3, 4}, q: 0.25, want: 1.75},
+		{sorted: []float64{1, 2, 3, 4, 5}, q: 0.5, want: 3},
+	}
+
+	for _, test := range tests {
+		if got := quantile(test.sorted, test.q); !closeTo(got, test.want) {
+			t.Errorf("quantile(%v, %v) = %v, want %v", test.sorted, test.q, got, test.want)
+		}
+	}
+}
+
+func TestNewPriceBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []float64
+		want   PriceBounds
+	}{
+		{
+			name:   "too few prices",
+			prices: []float64{10, 10, 1, 99999},
+			want:   PriceBounds{},
+		},
+		{
+			// Median 12, and a deviation of 1 scaled to 3.5 standard deviations
+			name:   "median absolute deviation",
+			prices: []float64{14, 10, 13, 11, 12},
+			want:   PriceBounds{Lower: 12 - 3.5*madScale, Upper: 12 + 3.5*madScale, Valid: true},
+		},
+		{
+			// Most prices are 10, so the deviation is zero and the quartiles 10 and 17.5 are used
+			name:   "zero deviation falls back to quartiles",
+			prices: []float64{10, 10, 10, 10, 20, 30},
+			want:   PriceBounds{Lower: 10 - 1.5*7.5, Upper: 17.5 + 1.5*7.5, Valid: true},
+		},
+		{
+			// The middle half are all 10, so half the median is allowed either side
+			name:   "zero spread",
+			prices: []float64{10, 10, 1, 10, 10, 10},
+			want:   PriceBounds{Lower: 5, Upper: 15, Valid: true},
+		},
+	}
+
+	for _, test := range tests {
+		got := NewPriceBounds(test.prices)
+
+		if got.Valid != test.want.Valid || !closeTo(got.Lower, test.want.Lower) || !closeTo(got.Upper, test.want.Upper) {
+			t.Errorf("%s: NewPriceBounds(%v) = %+v, want %+v", test.name, test.prices, got, test.want)
+		}
+	}
+}
+
+func TestFilterOutliers(t *testing.T) {
+	tests := []struct {
+		name    string
+		orders  []BookOrder
+		kept    []string
+		removed int
+	}{
+		{
+			name: "too few prices to tell",
+			orders: []BookOrder{
+				testOrder("a", nil, 50, 10),
+				testOrder("b", nil, 55, 10),
+				testOrder("c", nil, 5000, 10),
+			},
+			kept:    []string{"a", "b", "c"},
+			removed: 0,
+		},
+		{
+			name: "low reputation",
+			orders: []BookOrder{
+				testOrder("a", nil, 50, 10),
+				testOrder("b", nil, 50, 0),
+			},
+			kept:    []string{"a"},
+			removed: 1,
+		},
+		{
+			// Together the maxed mods would look like trolls, but each rank has its own bounds
+			name: "separate bounds per rank",
+			orders: []BookOrder{
+				testOrder("r0-a", intPointer(0), 10, 10),
+				testOrder("r0-b", intPointer(0), 11, 10),
+				testOrder("r10-a", intPointer(10), 100, 10),
+				testOrder("r0-c", intPointer(0), 12, 10),
+				testOrder("r10-b", intPointer(10), 105, 10),
+				testOrder("r0-d", intPointer(0), 13, 10),
+				testOrder("r10-c", intPointer(10), 110, 10),
+				testOrder("r0-e", intPointer(0), 14, 10),
+				testOrder("r10-d", intPointer(10), 115, 10),
+				testOrder("r0-bait", intPointer(0), 1, 10),
+				testOrder("r10-e", intPointer(10), 120, 10),
+				testOrder("r10-troll", intPointer(10), 99999, 10),
+			},
+			kept:    []string{"r0-a", "r0-b", "r10-a", "r0-c", "r10-b", "r0-d", "r10-c", "r0-e", "r10-d", "r10-e"},
+			removed: 2,
+		},
+	}
+
+	for _, test := range tests {
+		kept, removed := FilterOutliers(test.orders)
+
+		var ids []string
+
+		for _, order := range kept {
+			ids = append(ids, order.ID)
+		}
+
+		if !reflect.DeepEqual(ids, test.kept) || removed != test.removed {
+			t.Errorf("%s: FilterOutliers() = %v, %d, want %v, %d", test.name, ids, removed, test.kept, test.removed)
+		}
+	}
+}
+
+func testOrder(id string, rank *int, price int, reputation int) BookOrder {
+	return BookOrder{ID: id, Rank: rank, Price: price, Reputation: reputation}
+}
+
+func intPointer(value int) *int {
+	return &value
+}
+
+func closeTo(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// How long a reported status is trusted without hearing about the user again
+const PresenceLifetime = time.Minute * 30
+
+// The statuses a warframe.market user can have
+const (
+	PresenceInGame  = "ingame"
+	PresenceOnline  = "online"
+	PresenceOffline = "offline"
+)
+
+// Presence is the last known status of a warframe.market user
+type Presence struct {
+	Status     string    // Can be "ingame", "online", "invisible" or "offline"
+	LastSeen   time.Time // When warframe.market last saw the user, if known
+	Name       string    // Their in-game name, if known
+	Locale     string    // Their warframe.market locale, if known
+	ObservedAt time.Time // When we were told about the status
+}
+
+// presenceChange is a change of a user's status, waiting to be told to the listeners
+type presenceChange struct {
+	userID   string
+	previous string
+	current  string
+}
+
+// PresenceIndex keeps the status of every warframe.market user seen on an order or a profile
+type PresenceIndex struct {
+	mu        sync.RWMutex
+	users     map[string]Presence
+	listeners []func(userID string, previous string, current string)
+
+	// The changes the listeners haven't been told about yet, in the order they happened
+	pendingMu sync.Mutex
+	pending   []presenceChange
+	wake      chan struct{}
+}
+
+var Presences = NewPresenceIndex()
+
+func NewPresenceIndex() *PresenceIndex {
+	index := &PresenceIndex{
+		users: map[string]Presence{},
+		wake:  make(chan struct{}, 1),
+	}
+
+	go index.notify()
+
+	return index
+}
+
+// OnChange subscribes the listener to every change of a known user's status.
+// Users seen for the first time don't change status, so a snapshot can't flood the listeners.
+// Listeners are called one at a time in the background, in the order the changes happened, so they never hold up the socket.
+func (p *PresenceIndex) OnChange(listener func(userID string, previous string, current string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.listeners = append(p.listeners, listener)
+}
+
+// Observe records the status of the user, as reported by an order or their profile.
+// The name and locale are kept from earlier observations when they aren't given.
+func (p *PresenceIndex) Observe(userID string, observed Presence) {
+	if userID == "" || observed.Status == "" {
+		return
+	}
+
+	now := time.Now()
+
+	p.mu.Lock()
+
+	existing, known := p.users[userID]
+	known = known && now.Sub(existing.ObservedAt) < PresenceLifetime
+
+	if observed.LastSeen.IsZero() || observed.LastSeen.Before(existing.LastSeen) {
+		observed.LastSeen = existing.LastSeen
+	}
+
+	if observed.Name == "" {
+		observed.Name = existing.Name
+	}
+
+	if observed.Locale == "" {
+		observed.Locale = existing.Locale
+	}
+
+	observed.ObservedAt = now
+	p.users[userID] = observed
+
+	p.mu.Unlock()
+
+	if !known || existing.Status == observed.Status {
+		return
+	}
+
+	p.pendingMu.Lock()
+	p.pending = append(p.pending, presenceChange{userID: userID, previous: existing.Status, current: observed.Status})
+	p.pendingMu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// notify tells the listeners about every change of status, until the program exits
+func (p *PresenceIndex) notify() {
+	for range p.wake {
+		p.pendingMu.Lock()
+		pending := p.pending
+		p.pending = nil
+		p.pendingMu.Unlock()
+
+		p.mu.RLock()
+		listeners := p.listeners
+		p.mu.RUnlock()
+
+		for _, change := range pending {
+			for _, listener := range listeners {
+				p.tell(listener, change)
+			}
+		}
+	}
+}
+
+// tell calls a single listener, isolating any panic to that listener
+func (p *PresenceIndex) tell(listener func(userID string, previous string, current string), change presenceChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Presence listener for %s panicked: %v\n%s", change.userID, r, debug.Stack())
+		}
+	}()
+
+	listener(change.userID, change.previous, change.current)
+}
+
+// Status returns the status of the user, or an empty string if it isn't known or no longer trusted
+func (p *PresenceIndex) Status(userID string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	presence, ok := p.users[userID]
+
+	if !ok || time.Since(presence.ObservedAt) > PresenceLifetime {
+		return ""
+	}
+
+	return presence.Status
+}
+
+// Get returns everything known about the status of the user
+func (p *PresenceIndex) Get(userID string) (Presence, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	presence, ok := p.users[userID]
+
+	return presence, ok
+}
+
+// Identity returns what is known about the user from their orders and profile, e.g. to resolve the sender of a message.
+// Only the ID is set if the user hasn't been seen recently.
+func (p *PresenceIndex) Identity(userID string) SocketIdentity {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	presence := p.users[userID]
+
+	return SocketIdentity{ID: userID, Name: presence.Name, Locale: presence.Locale}
+}
+
+// Evict forgets the users whose status is no longer trusted
+func (p *PresenceIndex) Evict() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for userID, presence := range p.users {
+		if time.Since(presence.ObservedAt) > PresenceLifetime {
+			delete(p.users, userID)
+		}
+	}
+}
+
+// Watch evicts the stale statuses forever
+func (p *PresenceIndex) Watch(interval time.Duration) {
+	for {
+		p.Evict()
+		time.Sleep(interval)
+	}
+}
+
+// PresenceRank orders statuses by how likely the user is to trade right now, the lowest first
+func PresenceRank(status string) int {
+	switch status {
+	case PresenceInGame:
+		return 0
+	case PresenceOnline:
+		return 1
+	case PresenceOffline:
+		return 3
+	}
+
+	return 2
+}
+
+// IsOnline returns true if the user can trade right now
+func IsOnline(status string) bool {
+	return status == PresenceInGame || status == PresenceOnline
+}
@@ -39,6 +39,28 @@ func GetActivePriceAlertsForUser(userId string) ([]*Alert, error) {
 	return alerts, nil
 }
 
+// Get a single price alert belonging to the given user
+func GetPriceAlertForUser(userId string, alertId uint32) (*Alert, error) {
+	var alert Alert
+
+	err := DB.Inner.Where("id = ? AND user_id = ?", alertId, userId).First(&alert).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &alert, nil
+}
+
+// Count the active price alerts of a given user
+func CountActivePriceAlertsForUser(userId string) (int64, error) {
+	var count int64
+
+	err := DB.Inner.Model(&Alert{}).Where("user_id = ? AND active = ?", userId, true).Count(&count).Error
+
+	return count, err
+}
+
 // Add a new price alert
 func AddPriceAlert(alert *Alert) error {
 	return DB.Inner.Create(alert).Error
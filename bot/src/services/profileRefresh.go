@@ -0,0 +1,156 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	// How often every linked profile is read again from warframe.market
+	ProfileRefreshInterval = time.Hour * 24
+	// The shortest wait between two refreshes, leaving most of the rate limit to everything else
+	profileRefreshMinDelay = time.Second * 5
+)
+
+// RunProfileRefresh re-reads one linked profile at a time, spreading all of them evenly across the refresh interval
+func RunProfileRefresh() {
+	for {
+		refreshed, err := DB.RefreshNextProfile()
+
+		if err != nil {
+			log.Printf("Error refreshing a linked profile: %s", err)
+		}
+
+		delay, err := DB.profileRefreshDelay()
+
+		if err != nil {
+			log.Printf("Error counting linked profiles: %s", err)
+			delay = profileRefreshMinDelay
+		}
+
+		// Nothing is due, so wait for the next account to become due
+		if !refreshed && err == nil {
+			delay = max(delay, time.Minute)
+		}
+
+		time.Sleep(delay)
+	}
+}
+
+// profileRefreshDelay spreads refreshing every linked account across the refresh interval
+func (db *Database) profileRefreshDelay() (time.Duration, error) {
+	var count int64
+
+	err := db.Inner.Model(&LinkedAccount{}).Where("stale = ?", false).Count(&count).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 0 {
+		return ProfileRefreshInterval, nil
+	}
+
+	return max(ProfileRefreshInterval/time.Duration(count), profileRefreshMinDelay), nil
+}
+
+// RefreshNextProfile refreshes the linked account which was refreshed longest ago, if it is due.
+// Stale accounts are skipped until their new name is seen. Returns false if no account is due.
+func (db *Database) RefreshNextProfile() (bool, error) {
+	var account LinkedAccount
+
+	err := db.Inner.
+		Where("stale = ?", false).
+		Where("refreshed_at IS NULL OR refreshed_at < ?", time.Now().Add(-ProfileRefreshInterval)).
+		Order("refreshed_at NULLS FIRST").
+		Limit(1).
+		Find(&account).Error
+
+	if err != nil {
+		return false, err
+	}
+
+	if account.ID == 0 {
+		return false, nil
+	}
+
+	return true, db.RefreshLinkedAccount(&account)
+}
+
+// RefreshLinkedAccount reads the account's profile from warframe.market, and updates what we know about it.
+// Its reputation is recorded, and it is flagged if warframe.market has banned it.
+// An account which can't be found is marked stale, rather than looking up the old name every day.
+func (db *Database) RefreshLinkedAccount(account *LinkedAccount) error {
+	now := time.Now()
+	profile, err := API.GetUser(account.Username)
+
+	// Profiles are looked up by name, so a renamed account can't be found until its new name is seen on the socket
+	if errors.Is(err, ErrProfileNotFound) || (err == nil && profile.ID != account.WfmID) {
+		log.Printf("The linked profile %s (%s) was not found under that name, it may have been renamed", account.Username, account.WfmID)
+
+		return db.Inner.Model(account).Updates(map[string]interface{}{
+			"stale":        true,
+			"refreshed_at": now,
+		}).Error
+	}
+
+	if err != nil {
+		return err
+	}
+
+	Presences.Observe(account.WfmID, Presence{
+		Status:   profile.Status,
+		LastSeen: profile.LastSeen,
+		Name:     profile.IngameName,
+		Locale:   profile.Locale,
+	})
+
+	err = db.ObserveAccountName(account.WfmID, profile.IngameName)
+
+	if err != nil {
+		return err
+	}
+
+	newlyBanned := profile.Banned && !account.Banned
+
+	err = db.Inner.Model(account).Updates(map[string]interface{}{
+		"reputation":   profile.Reputation,
+		"banned":       profile.Banned,
+		"stale":        false,
+		"last_seen":    sql.NullTime{Time: profile.LastSeen, Valid: !profile.LastSeen.IsZero()},
+		"refreshed_at": now,
+	}).Error
+
+	if err != nil {
+		return err
+	}
+
+	if newlyBanned {
+		log.Printf("The linked profile %s (%s) of %s has been banned", profile.IngameName, account.WfmID, account.UserID)
+
+		if accountBannedHook != nil {
+			go accountBannedHook(account)
+		}
+	}
+
+	err = db.Create(&ReputationRecord{
+		WfmID:      account.WfmID,
+		Reputation: int32(profile.Reputation),
+		RecordedAt: now,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// The locale of the default account is the one used to reply on warframe.market, see User.WfmLocale
+	if profile.Locale != "" && account.IsDefault {
+		err = db.Inner.Model(&User{}).
+			Where("id = ?", account.UserID).
+			Update("wfm_locale", profile.Locale).Error
+	}
+
+	return err
+}
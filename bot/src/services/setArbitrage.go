@@ -0,0 +1,409 @@
+package services
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sets are only analyzed on the platform the socket streams orders for
+const SetArbitragePlatform = "pc"
+
+// How long the books of a set may go without a snapshot while the analyzer walks through every set
+const setBookMaxAge = time.Hour * 2
+
+// How long to wait between sets, so the analyzer leaves room in the rate limit for commands
+const setScanDelay = time.Second * 30
+
+// How long the relation between sets and their parts is cached before being read from the database again
+const setCatalogueLifetime = time.Hour
+
+// The ways to profit from the difference between a set and its parts
+const (
+	SetBuild = "build" // Buy the parts, and sell the set
+	SetSplit = "split" // Buy the set, and sell its parts
+)
+
+// SetLeg is the set or one of its parts, as bought or sold in an opportunity
+type SetLeg struct {
+	ItemID   string
+	Slug     string
+	Quantity int // How many are needed for the set
+	Price    int // What buying or selling that many costs or earns
+}
+
+// SetOpportunity is the profit of building or splitting a set at the current best prices
+type SetOpportunity struct {
+	SetID     string
+	SetSlug   string
+	Direction string // See SetBuild and SetSplit
+	Cost      int    // What buying the set or its parts costs
+	Revenue   int    // What selling the set or its parts earns
+	Margin    int    // Revenue - Cost
+	Liquidity int    // How many times the flip can be repeated before a side runs out of orders
+	Set       SetLeg
+	Parts     []SetLeg
+	UpdatedAt time.Time
+}
+
+// Score ranks opportunities by margin, favouring the ones which can be repeated, as orders disappear quickly
+func (o *SetOpportunity) Score() float64 {
+	return float64(o.Margin) * math.Log1p(float64(o.Liquidity))
+}
+
+// setDefinition is a set, and the parts which build it
+type setDefinition struct {
+	set   Item
+	parts []Item
+}
+
+// SetAnalyzer compares the price of every set against the sum of its parts, from the live order book
+type SetAnalyzer struct {
+	mu            sync.RWMutex
+	sets          map[string]*setDefinition // Set item ID -> set
+	members       map[string]string         // Item ID of the set or a part -> set item ID
+	loaded        time.Time
+	opportunities map[string]*SetOpportunity // Set item ID -> the better way to flip it
+	hook          func(opportunity *SetOpportunity, previous *SetOpportunity)
+}
+
+var Sets = NewSetAnalyzer()
+
+func NewSetAnalyzer() *SetAnalyzer {
+	return &SetAnalyzer{
+		sets:          map[string]*setDefinition{},
+		members:       map[string]string{},
+		opportunities: map[string]*SetOpportunity{},
+	}
+}
+
+// SetOpportunityHook sets the function called after an opportunity is analyzed, with the previous one if any
+func (a *SetAnalyzer) SetOpportunityHook(hook func(opportunity *SetOpportunity, previous *SetOpportunity)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.hook = hook
+}
+
+// load reads the sets and their parts from the database, if the cache has expired.
+// Every item of a set shares the set's manifest ID in PartOf, and the set itself is the one whose slug ends in "_set".
+func (a *SetAnalyzer) load() error {
+	a.mu.RLock()
+	fresh := time.Since(a.loaded) < setCatalogueLifetime
+	a.mu.RUnlock()
+
+	if fresh {
+		return nil
+	}
+
+	var items []Item
+
+	err := DB.Inner.Where("is_set AND part_of IS NOT NULL").Find(&items).Error
+
+	if err != nil {
+		return err
+	}
+
+	groups := map[string]*setDefinition{}
+
+	for _, item := range items {
+		group, ok := groups[item.PartOf.String]
+
+		if !ok {
+			group = &setDefinition{}
+			groups[item.PartOf.String] = group
+		}
+
+		if strings.HasSuffix(item.Slug, "_set") {
+			group.set = item
+		} else {
+			group.parts = append(group.parts, item)
+		}
+	}
+
+	sets := map[string]*setDefinition{}
+	members := map[string]string{}
+
+	for _, group := range groups {
+		if group.set.ID == "" || len(group.parts) == 0 {
+			continue
+		}
+
+		sets[group.set.ID] = group
+		members[group.set.ID] = group.set.ID
+
+		for _, part := range group.parts {
+			members[part.ID] = group.set.ID
+		}
+	}
+
+	a.mu.Lock()
+	a.sets = sets
+	a.members = members
+	a.loaded = time.Now()
+	a.mu.Unlock()
+
+	return nil
+}
+
+// OnBookChange analyzes the set again when the orders of it or one of its parts change
+func (a *SetAnalyzer) OnBookChange(itemID string, platform string) {
+	if platform != SetArbitragePlatform {
+		return
+	}
+
+	if err := a.load(); err != nil {
+		log.Printf("Error loading the sets: %s", err)
+		return
+	}
+
+	a.mu.RLock()
+	setID, ok := a.members[itemID]
+	a.mu.RUnlock()
+
+	if ok {
+		a.Analyze(setID)
+	}
+}
+
+// Run walks through every set forever, keeping the books of the set and its parts fresh enough to analyze
+func (a *SetAnalyzer) Run() {
+	for {
+		if err := a.load(); err != nil {
+			log.Printf("Error loading the sets: %s", err)
+			time.Sleep(setScanDelay)
+			continue
+		}
+
+		a.mu.RLock()
+		sets := make([]*setDefinition, 0, len(a.sets))
+
+		for _, set := range a.sets {
+			sets = append(sets, set)
+		}
+
+		a.mu.RUnlock()
+
+		for _, set := range sets {
+			for _, item := range append([]Item{set.set}, set.parts...) {
+				err := Orders.Refresh(item.ID, item.Slug, SetArbitragePlatform, setBookMaxAge)
+
+				if err != nil {
+					log.Printf("Error refreshing the order book of %s: %s", item.Slug, err)
+				}
+			}
+
+			a.Analyze(set.set.ID)
+
+			time.Sleep(setScanDelay)
+		}
+
+		// Don't spin when there are no sets yet, e.g. before the first item sync
+		if len(sets) == 0 {
+			time.Sleep(setScanDelay)
+		}
+	}
+}
+
+// Analyze works out the better way to flip the set from the current best prices, or nil if either way lacks orders
+func (a *SetAnalyzer) Analyze(setID string) *SetOpportunity {
+	a.mu.RLock()
+	set, ok := a.sets[setID]
+	hook := a.hook
+	a.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	opportunity := bestOpportunity(set)
+
+	a.mu.Lock()
+	previous := a.opportunities[setID]
+
+	if opportunity == nil {
+		delete(a.opportunities, setID)
+	} else {
+		a.opportunities[setID] = opportunity
+	}
+
+	a.mu.Unlock()
+
+	if opportunity != nil && hook != nil {
+		go hook(opportunity, previous)
+	}
+
+	return opportunity
+}
+
+// Top returns up to limit profitable opportunities which can be repeated at least minLiquidity times, the best first.
+// When direction is given, only opportunities flipping that way are returned.
+func (a *SetAnalyzer) Top(limit int, minLiquidity int, direction string) []SetOpportunity {
+	a.mu.RLock()
+
+	var top []SetOpportunity
+
+	for _, opportunity := range a.opportunities {
+		if opportunity.Margin <= 0 || opportunity.Liquidity < minLiquidity {
+			continue
+		}
+
+		if direction != "" && opportunity.Direction != direction {
+			continue
+		}
+
+		top = append(top, *opportunity)
+	}
+
+	a.mu.RUnlock()
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Score() != top[j].Score() {
+			return top[i].Score() > top[j].Score()
+		}
+
+		return top[i].Margin > top[j].Margin
+	})
+
+	if limit > 0 && len(top) > limit {
+		top = top[:limit]
+	}
+
+	return top
+}
+
+// Count returns how many sets have been analyzed, and how many are known
+func (a *SetAnalyzer) Count() (int, int) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return len(a.opportunities), len(a.sets)
+}
+
+// bestOpportunity compares building and splitting the set, and returns the more profitable one
+func bestOpportunity(set *setDefinition) *SetOpportunity {
+	build := buildOpportunity(set)
+	split := splitOpportunity(set)
+
+	if build == nil || (split != nil && split.Margin > build.Margin) {
+		return split
+	}
+
+	return build
+}
+
+// buildOpportunity buys the parts from the cheapest sell orders, and sells the set to the best buy orders.
+// It can be repeated until the buyers of the set, or the sellers of a part, run out.
+func buildOpportunity(set *setDefinition) *SetOpportunity {
+	leg, liquidity, ok := tradeLeg(set.set, 1, OrderTypeBuy)
+
+	if !ok {
+		return nil
+	}
+
+	opportunity := &SetOpportunity{
+		SetID:     set.set.ID,
+		SetSlug:   set.set.Slug,
+		Direction: SetBuild,
+		Revenue:   leg.Price,
+		Liquidity: liquidity,
+		Set:       leg,
+		UpdatedAt: time.Now(),
+	}
+
+	for _, part := range set.parts {
+		leg, liquidity, ok := tradeLeg(part, max(int(part.NumberForSet), 1), OrderTypeSell)
+
+		if !ok {
+			return nil
+		}
+
+		opportunity.Cost += leg.Price
+		opportunity.Liquidity = min(opportunity.Liquidity, liquidity)
+		opportunity.Parts = append(opportunity.Parts, leg)
+	}
+
+	opportunity.Margin = opportunity.Revenue - opportunity.Cost
+
+	return opportunity
+}
+
+// splitOpportunity buys the set from the cheapest sell orders, and sells each part to the best buy orders.
+// It can be repeated until the sellers of the set, or the buyers of a part, run out.
+func splitOpportunity(set *setDefinition) *SetOpportunity {
+	leg, liquidity, ok := tradeLeg(set.set, 1, OrderTypeSell)
+
+	if !ok {
+		return nil
+	}
+
+	opportunity := &SetOpportunity{
+		SetID:     set.set.ID,
+		SetSlug:   set.set.Slug,
+		Direction: SetSplit,
+		Cost:      leg.Price,
+		Liquidity: liquidity,
+		Set:       leg,
+		UpdatedAt: time.Now(),
+	}
+
+	for _, part := range set.parts {
+		leg, liquidity, ok := tradeLeg(part, max(int(part.NumberForSet), 1), OrderTypeBuy)
+
+		if !ok {
+			return nil
+		}
+
+		opportunity.Revenue += leg.Price
+		opportunity.Liquidity = min(opportunity.Liquidity, liquidity)
+		opportunity.Parts = append(opportunity.Parts, leg)
+	}
+
+	opportunity.Margin = opportunity.Revenue - opportunity.Cost
+
+	return opportunity
+}
+
+// tradeLeg prices trading the needed number of the item with its orders of the given type, the best first.
+// Returns the leg, how many times it can be repeated before the orders run out, and false if there are too few orders.
+func tradeLeg(item Item, needed int, orderType OrderType) (SetLeg, int, bool) {
+	orders, _ := Orders.Clean(item.ID, SetArbitragePlatform, nil, string(orderType))
+	price, ok := fill(orders, needed)
+
+	if !ok {
+		return SetLeg{}, 0, false
+	}
+
+	return SetLeg{ItemID: item.ID, Slug: item.Slug, Quantity: needed, Price: price}, totalQuantity(orders) / needed, true
+}
+
+// fill returns what trading the given number of items costs or earns, walking from the best order down the book
+func fill(orders []BookOrder, needed int) (int, bool) {
+	total := 0
+
+	for _, order := range orders {
+		taken := min(order.Quantity, needed)
+		total += taken * order.Price
+		needed -= taken
+
+		if needed == 0 {
+			return total, true
+		}
+	}
+
+	return 0, false
+}
+
+// totalQuantity returns the number of items across every order
+func totalQuantity(orders []BookOrder) int {
+	total := 0
+
+	for _, order := range orders {
+		total += order.Quantity
+	}
+
+	return total
+}
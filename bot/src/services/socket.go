@@ -1,12 +1,15 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"time"
+	"runtime/debug"
+	"sync"
 	"vaportrader/src/constants"
 
 	"github.com/bwmarrin/discordgo"
@@ -14,69 +17,187 @@ import (
 )
 
 type SocketClient struct {
-	Socket      *websocket.Conn
-	OrderHook   func(order *SubscriptionsNewOrder)
-	MessageHook func(message SocketPrivateMessage)
-	PMHook      func(message *NewMessage)
-	PendingPMs  map[string]*PendingMessage
-	PMQueue     chan *SendMessage
-	Status      UserStatus
-	Session     *discordgo.Session
+	Socket  *websocket.Conn
+	Events  *SocketDispatcher
+	Outbox  *Outbox
+	Status  UserStatus
+	Session *discordgo.Session
+
+	// Guards writes to the socket, and the connection state
+	writeMu   sync.Mutex
+	connected bool
+
+	onlineMu    sync.RWMutex
+	onlineCount SocketOnlineCount
+
+	// Private messages are handled one at a time per chat, but chats don't wait for each other
+	chats *chatWorkers
 }
 
 func NewSocketClient(s *discordgo.Session) *SocketClient {
-	return &SocketClient{
-		Socket:      nil,
-		OrderHook:   nil,
-		MessageHook: nil,
-		PMHook:      nil,
-		PMQueue:     make(chan *SendMessage),
-		PendingPMs:  make(map[string]*PendingMessage),
-		Status:      UserStatusUnknown,
-		Session:     s,
+	client := &SocketClient{
+		Socket:  nil,
+		Events:  NewSocketDispatcher(),
+		Status:  UserStatusUnknown,
+		Session: s,
+		chats:   newChatWorkers(),
 	}
+
+	client.Outbox = NewOutbox(client)
+
+	On(client.Events, EventOnlineCount, func(count *SocketOnlineCount) error {
+		client.onlineMu.Lock()
+		client.onlineCount = *count
+		client.onlineMu.Unlock()
+
+		return nil
+	})
+
+	return client
 }
 
-func (s *SocketClient) SendPM(message string, userID string) (*MessageAcknowledgement, error) {
-	pm, channel, err := SendPM(message, userID)
+// OnlineCount returns the most recent number of users online on warframe.market
+func (s *SocketClient) OnlineCount() SocketOnlineCount {
+	s.onlineMu.RLock()
+	defer s.onlineMu.RUnlock()
 
-	if err != nil {
-		return nil, err
+	return s.onlineCount
+}
+
+// Send queues a private message in the outbox, and waits until warframe.market confirms it
+func (s *SocketClient) Send(ctx context.Context, chatID string, message string) (*MessageAcknowledgement, error) {
+	return s.Outbox.Send(ctx, chatID, message)
+}
+
+// SendPM queues a private message in the outbox, without waiting for it to be delivered
+func (s *SocketClient) SendPM(message string, chatID string) error {
+	return s.Outbox.Queue(chatID, message)
+}
+
+// Connected returns true while the socket is open
+func (s *SocketClient) Connected() bool {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.connected
+}
+
+// write sends the message over the socket. The socket only supports one writer at a time.
+func (s *SocketClient) write(message any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if !s.connected {
+		return errors.New("the socket is not connected")
 	}
 
-	// Add the pending message to the map
-	s.PendingPMs[pm.TempID] = channel
+	return s.Socket.WriteJSON(message)
+}
 
-	// Queue the message to be sent
-	s.PMQueue <- pm
+// setConnection swaps in a new connection, or marks the socket as disconnected when nil
+func (s *SocketClient) setConnection(conn *websocket.Conn) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 
-	// Wait for confirmation
-	result := <-channel.Confirmation
+	if conn != nil {
+		s.Socket = conn
+	}
 
-	return result, nil
+	s.connected = conn != nil
 }
 
+// SetPMHook subscribes the hook to every new private message.
+// Messages of a chat are handled in order, while a slow reply in one chat doesn't hold up the others.
 func (s *SocketClient) SetPMHook(hook func(message *NewMessage)) {
-	s.PMHook = hook
+	On(s.Events, EventNewMessage, func(message *NewMessage) error {
+		s.chats.run(message.ChatID, func() {
+			hook(message)
+		})
+		return nil
+	})
 }
 
+// SetOrderHook subscribes the hook to every new order
 func (s *SocketClient) SetOrderHook(hook func(order *SubscriptionsNewOrder)) {
-	s.OrderHook = hook
+	On(s.Events, EventNewOrder, func(order *SubscriptionsNewWrappedOrder) error {
+		hook(&order.Order)
+		return nil
+	})
 }
 
+// SetMessageHook subscribes the hook to every new private message, acknowledging it as read.
+// Messages of a chat are handled in order, while a slow reply in one chat doesn't hold up the others.
 func (s *SocketClient) SetMessageHook(hook func(message SocketPrivateMessage)) {
-	s.MessageHook = hook
+	On(s.Events, EventNewMessage, func(message *NewMessage) error {
+		s.chats.run(message.ChatID, func() {
+			hook(ProcessPM(SocketMessage[NewMessage]{Type: EventNewMessage, Data: *message}))
+		})
+		return nil
+	})
+}
+
+// chatWorkers runs the work of each chat one at a time, in the order it was queued, with chats running concurrently.
+// A chat only has a goroutine while it has work queued.
+type chatWorkers struct {
+	mu     sync.Mutex
+	queues map[string][]func() // Chat ID -> the work waiting, present while a goroutine drains it
+}
+
+func newChatWorkers() *chatWorkers {
+	return &chatWorkers{queues: map[string][]func(){}}
+}
+
+// run queues the work for the chat, starting a goroutine for the chat if it has none
+func (w *chatWorkers) run(chatID string, work func()) {
+	w.mu.Lock()
+	queue, running := w.queues[chatID]
+	w.queues[chatID] = append(queue, work)
+	w.mu.Unlock()
+
+	if !running {
+		go w.drain(chatID)
+	}
+}
+
+// drain runs the work of the chat until its queue is empty
+func (w *chatWorkers) drain(chatID string) {
+	for {
+		w.mu.Lock()
+		queue := w.queues[chatID]
+
+		if len(queue) == 0 {
+			delete(w.queues, chatID)
+			w.mu.Unlock()
+			return
+		}
+
+		w.queues[chatID] = queue[1:]
+		w.mu.Unlock()
+
+		w.call(chatID, queue[0])
+	}
+}
+
+// call runs a single piece of work, isolating any panic to it
+func (w *chatWorkers) call(chatID string, work func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Private message handler for chat %s panicked: %v\n%s", chatID, r, debug.Stack())
+		}
+	}()
+
+	work()
 }
 
 func (s *SocketClient) SetStatus(status UserStatus) {
-	s.Socket.WriteJSON(SocketMessage[UserStatus]{
+	_ = s.write(SocketMessage[UserStatus]{
 		Type: "@WS/USER/SET_STATUS",
 		Data: status,
 	})
 }
 
 func (s *SocketClient) Subscribe(event string) {
-	s.Socket.WriteJSON(SocketMessage[any]{
+	_ = s.write(SocketMessage[any]{
 		Type: "@WS/SUBSCRIBE/" + event,
 	})
 }
@@ -86,6 +207,8 @@ var Socket *SocketClient
 func InitSocket(s *discordgo.Session) {
 	Socket = NewSocketClient(s)
 	ConfigureSocket()
+
+	go Socket.Outbox.Run()
 }
 
 func ConfigureSocket() {
@@ -99,7 +222,7 @@ func ConfigureSocket() {
 		log.Fatal("dial:", err)
 	}
 
-	Socket.Socket = skt
+	Socket.setConnection(skt)
 
 	done := make(chan struct{})
 
@@ -116,130 +239,34 @@ func ConfigureSocket() {
 	go func() {
 		defer close(done)
 		for {
-			msg, raw, err := Socket.read()
+			raw, err := Socket.read()
 			if err != nil {
 				log.Printf("Socket closed unexpectedly")
 				log.Print(err.Error())
+				Socket.setConnection(nil)
 				go ConfigureSocket()
 				return
 			}
 
-			// Send all messages to the message hook if present
-			if Socket.MessageHook != nil {
-				privMessage, err := PayloadFrom[NewMessage](raw)
-
-				if err != nil {
-					log.Printf("error unmarshaling message: %s", err)
-					return
-				}
-
-				pm := ProcessPM(privMessage)
-				go Socket.MessageHook(pm)
-				continue
-			}
-
-			// Handle PM message delivery confirmations
-			if msg.Type == "@WS/chats/MESSAGE_SENT" {
-				ack, err := PayloadFrom[MessageAcknowledgement](raw)
-				if err != nil {
-					log.Printf("error unmarshaling message: %s", err)
-					ack.Data.Success = false
-					if _, ok := Socket.PendingPMs[ack.Data.TempID]; ok {
-						Socket.PendingPMs[ack.Data.TempID].Success = true
-						Socket.PendingPMs[ack.Data.TempID].Confirmation <- &ack.Data
-
-						delete(Socket.PendingPMs, ack.Data.TempID)
-					}
-				} else {
-					ack.Data.Success = true
-					if _, ok := Socket.PendingPMs[ack.Data.TempID]; ok {
-						Socket.PendingPMs[ack.Data.TempID].Success = true
-						Socket.PendingPMs[ack.Data.TempID].Confirmation <- &ack.Data
-						delete(Socket.PendingPMs, ack.Data.TempID)
-					}
-				}
-			}
-
-			// If we receive a new PM, check if we have a hook for it and process accordingly
-			if Socket.PMHook != nil && msg.Type == "@WS/chats/NEW_MESSAGE" {
-				nmsg, err := PayloadFrom[NewMessage](raw)
-				if err != nil {
-					log.Printf("error unmarshaling message: %s", err)
-					return
-				}
-				go Socket.PMHook(&nmsg.Data)
-				continue
-			}
-
-			// If we receive a new order, check if we have a hook for it and process accordingly
-			if msg.Type == "@WS/SUBSCRIPTIONS/MOST_RECENT/NEW_ORDER" {
-				if Socket.OrderHook != nil {
-					order, err := PayloadFrom[SubscriptionsNewWrappedOrder](raw)
-					if err != nil {
-						log.Printf("error unmarshaling message: %s", err)
-						return
-					}
-					go Socket.OrderHook(&order.Data.Order)
-					continue
-				}
-			}
-
-			log.Printf("unhandled message type: %s", msg.Type)
+			Socket.Events.Dispatch(raw)
 		}
 	}()
 
-	go func() {
-		for pm := range Socket.PMQueue {
-			log.Printf("sending pm to channel %s", pm.ChatID)
-			log.Print(pm.Message)
-			err := Socket.Socket.WriteJSON(SocketMessage[*SendMessage]{
-				Type: "@WS/chats/SEND_MESSAGE",
-				Data: pm,
-			})
-
-			if err != nil {
-				log.Printf("error sending pm: %s", err)
-			}
-		}
-	}()
-
-	go func() {
-		for {
+	// Anything in flight on the previous connection may have been lost, so send it again
+	err = Socket.Outbox.Requeue()
 
-			for key, pm := range Socket.PendingPMs {
-
-				// Check if the message is older than 5 seconds
-				cutoff := pm.Time.Add(time.Second * 5)
-
-				if time.Now().After(cutoff) {
-					pm.Confirmation <- &MessageAcknowledgement{
-						Message: nil,
-						TempID:  key,
-						Success: false,
-					}
-					delete(Socket.PendingPMs, key)
-				}
-			}
-
-			time.Sleep(time.Second * 1)
-		}
-	}()
-}
-
-func (s *SocketClient) read() (*SocketMessage[any], []byte, error) {
-	_, message, err := s.Socket.ReadMessage()
 	if err != nil {
-		return nil, nil, err
+		log.Printf("error requeuing outbox messages: %s", err)
 	}
+}
 
-	var msg SocketMessage[any]
-
-	err = json.Unmarshal(message, &msg)
+func (s *SocketClient) read() ([]byte, error) {
+	_, message, err := s.Socket.ReadMessage()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return &msg, message, nil
+	return message, nil
 }
 
 func PayloadFrom[T any](msg []byte) (SocketMessage[T], error) {
@@ -257,7 +284,7 @@ func (s *SocketMessage[any]) Serialize() []byte {
 }
 
 func (m *NewMessage) Acknowledge() {
-	Socket.Socket.WriteJSON(SocketMessage[ReadMessage]{
+	_ = Socket.write(SocketMessage[ReadMessage]{
 		Type: "@WS/chats/MESSAGE_WAS_READ",
 		Data: ReadMessage{
 			MessageID: m.ID,
@@ -265,8 +292,26 @@ func (m *NewMessage) Acknowledge() {
 	})
 }
 
-func (m *NewMessage) Reply(message string) (*MessageAcknowledgement, error) {
-	return Socket.SendPM(message+"\n\n"+constants.WFMFooter, m.ChatID)
+func (m *NewMessage) Reply(message string) error {
+	return Socket.SendPM(FitPM(message, constants.WFMFooter), m.ChatID)
+}
+
+// FitPM joins the message and footer, shortening the message so the result fits in a single private message
+func FitPM(message string, footer string) string {
+	separator := "\n\n"
+
+	if footer == "" {
+		separator = ""
+	}
+
+	available := constants.WFMMessageLimit - len([]rune(footer)) - len([]rune(separator))
+	runes := []rune(message)
+
+	if len(runes) > available {
+		message = string(runes[:available-1]) + "…"
+	}
+
+	return message + separator + footer
 }
 
 func ProcessPM(msg SocketMessage[NewMessage]) SocketPrivateMessage {
@@ -281,8 +326,8 @@ func ProcessPM(msg SocketMessage[NewMessage]) SocketPrivateMessage {
 	return pm
 }
 
-func (pm *SocketPrivateMessage) Reply(message string) (*MessageAcknowledgement, error) {
-	return Socket.SendPM(message, pm.inner.ChatID)
+func (pm *SocketPrivateMessage) Reply(message string) error {
+	return Socket.SendPM(FitPM(message, ""), pm.inner.ChatID)
 }
 
 func (pm *SocketPrivateMessage) Acknowledge() {
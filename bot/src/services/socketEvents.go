@@ -0,0 +1,232 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// The types of the messages sent to us by the warframe.market socket
+const (
+	EventNewOrder     = "@WS/SUBSCRIPTIONS/MOST_RECENT/NEW_ORDER"
+	EventOrderUpdated = "@WS/SUBSCRIPTIONS/MOST_RECENT/UPDATED_ORDER"
+	EventOrderRemoved = "@WS/SUBSCRIPTIONS/MOST_RECENT/REMOVED_ORDER"
+	EventNewMessage   = "@WS/chats/NEW_MESSAGE"
+	EventMessageSent  = "@WS/chats/MESSAGE_SENT"
+	EventOnlineCount  = "@WS/MESSAGE/ONLINE_COUNT"
+	EventStatusChange = "@WS/USER/SET_STATUS"
+	EventError        = "@WS/ERROR"
+)
+
+// How many messages may wait for a subscription before the socket stops reading
+const socketQueueSize = 1024
+
+// socketHandler receives the raw message, and decodes the payload itself
+type socketHandler func(raw []byte) error
+
+// subscribedHandler is a handler, and the subscription which delivers messages to it
+type subscribedHandler struct {
+	subscription *SocketSubscription
+	handle       socketHandler
+}
+
+// socketDelivery is a message waiting in the queue of a subscription
+type socketDelivery struct {
+	event  string
+	handle socketHandler
+	raw    []byte
+}
+
+// SocketDispatcher routes the messages received from the socket to every handler subscribed to their type.
+// A handler which fails to decode its payload, returns an error, or panics does not affect any other handler.
+type SocketDispatcher struct {
+	mu       sync.RWMutex
+	handlers map[string][]subscribedHandler
+	Metrics  *SocketMetrics
+}
+
+func NewSocketDispatcher() *SocketDispatcher {
+	return &SocketDispatcher{
+		handlers: map[string][]subscribedHandler{},
+		Metrics:  NewSocketMetrics(),
+	}
+}
+
+// SocketSubscription delivers messages to its handlers one at a time, in the order they were received.
+// Handlers which rely on the order of different message types, e.g. an order being added and then removed,
+// must share a subscription.
+type SocketSubscription struct {
+	dispatcher *SocketDispatcher
+	queue      chan socketDelivery
+}
+
+// Subscription creates a subscription with its own queue, and starts delivering messages to it
+func (d *SocketDispatcher) Subscription() *SocketSubscription {
+	subscription := &SocketSubscription{
+		dispatcher: d,
+		queue:      make(chan socketDelivery, socketQueueSize),
+	}
+
+	go subscription.deliver()
+
+	return subscription
+}
+
+// deliver runs the handlers of the queued messages, one after the other
+func (s *SocketSubscription) deliver() {
+	for delivery := range s.queue {
+		s.dispatcher.run(delivery.event, delivery.handle, delivery.raw)
+	}
+}
+
+// On subscribes the handler to every message of the given type, decoding the payload into T.
+// Any number of handlers may subscribe to the same type. Each one has its own subscription, so a slow handler
+// doesn't hold up the others.
+func On[T any](d *SocketDispatcher, event string, handler func(payload *T) error) {
+	Handle(d.Subscription(), event, handler)
+}
+
+// Handle adds a handler of the given type to the subscription, decoding the payload into T
+func Handle[T any](s *SocketSubscription, event string, handler func(payload *T) error) {
+	d := s.dispatcher
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.handlers[event] = append(d.handlers[event], subscribedHandler{
+		subscription: s,
+		handle: func(raw []byte) error {
+			msg, err := PayloadFrom[T](raw)
+
+			if err != nil {
+				return fmt.Errorf("error unmarshaling payload: %w", err)
+			}
+
+			return handler(&msg.Data)
+		},
+	})
+}
+
+// Dispatch decodes the type of the message, and queues it for every subscribed handler.
+// This blocks while the queue of a subscription is full, so that no message is dropped or reordered.
+func (d *SocketDispatcher) Dispatch(raw []byte) {
+	var envelope SocketMessage[json.RawMessage]
+
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		d.Metrics.malformed()
+		return
+	}
+
+	d.mu.RLock()
+	handlers := d.handlers[envelope.Type]
+	d.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		d.Metrics.count(d.Metrics.Unhandled, envelope.Type)
+		return
+	}
+
+	d.Metrics.count(d.Metrics.Received, envelope.Type)
+
+	for _, handler := range handlers {
+		handler.subscription.queue <- socketDelivery{event: envelope.Type, handle: handler.handle, raw: raw}
+	}
+}
+
+// run calls a single handler, isolating any error or panic to that handler
+func (d *SocketDispatcher) run(event string, handler socketHandler, raw []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.Metrics.count(d.Metrics.Failed, event)
+			log.Printf("Socket handler for %s panicked: %v\n%s", event, r, debug.Stack())
+		}
+	}()
+
+	if err := handler(raw); err != nil {
+		d.Metrics.count(d.Metrics.Failed, event)
+		log.Printf("Socket handler for %s failed: %s", event, err)
+	}
+}
+
+// SocketMetrics counts the messages received from the socket, by their type
+type SocketMetrics struct {
+	mu sync.Mutex
+	// Messages passed to at least one handler
+	Received map[string]uint64
+	// Messages without any subscribed handler
+	Unhandled map[string]uint64
+	// Handlers which returned an error or panicked
+	Failed map[string]uint64
+	// Messages which could not be decoded at all
+	Malformed uint64
+}
+
+func NewSocketMetrics() *SocketMetrics {
+	return &SocketMetrics{
+		Received:  map[string]uint64{},
+		Unhandled: map[string]uint64{},
+		Failed:    map[string]uint64{},
+	}
+}
+
+// count increments the counter of the message type
+func (m *SocketMetrics) count(counts map[string]uint64, event string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts[event]++
+}
+
+func (m *SocketMetrics) malformed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Malformed++
+}
+
+// Snapshot returns a copy of the metrics, which is safe to read without holding the lock
+func (m *SocketMetrics) Snapshot() SocketMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return SocketMetricsSnapshot{
+		Received:  copyCounts(m.Received),
+		Unhandled: copyCounts(m.Unhandled),
+		Failed:    copyCounts(m.Failed),
+		Malformed: m.Malformed,
+	}
+}
+
+// SocketMetricsSnapshot is a point in time copy of the socket metrics
+type SocketMetricsSnapshot struct {
+	Received  map[string]uint64
+	Unhandled map[string]uint64
+	Failed    map[string]uint64
+	Malformed uint64
+}
+
+// UnhandledTypes returns the sorted message types which had no handler
+func (s SocketMetricsSnapshot) UnhandledTypes() []string {
+	types := make([]string, 0, len(s.Unhandled))
+
+	for event := range s.Unhandled {
+		types = append(types, event)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	copied := make(map[string]uint64, len(counts))
+
+	for key, value := range counts {
+		copied[key] = value
+	}
+
+	return copied
+}
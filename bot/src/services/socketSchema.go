@@ -2,8 +2,6 @@ package services
 
 import (
 	"time"
-
-	"github.com/teris-io/shortid"
 )
 
 type UserStatus string
@@ -48,6 +46,11 @@ type SubscriptionsNewOrder struct {
 	Item         PlatformItem `json:"item"`          // The item being ordered.
 }
 
+// SubscriptionsRemovedOrder is sent when an order is deleted, or closed after a trade
+type SubscriptionsRemovedOrder struct {
+	ID string `json:"order_id"` // The ID of the removed order.
+}
+
 // Platform User Info
 
 type PlatformUser struct {
@@ -99,25 +102,6 @@ type SendMessage struct {
 	TempID  string `json:"temp_id"` // The temporary ID of the message.
 }
 
-func SendPM(message string, chatID string) (*SendMessage, *PendingMessage, error) {
-	ack := &PendingMessage{
-		Confirmation: make(chan *MessageAcknowledgement),
-		Time:         time.Now(),
-		Success:      false,
-	}
-	tid, err := shortid.Generate()
-
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return &SendMessage{
-		ChatID:  chatID,
-		Message: message,
-		TempID:  tid,
-	}, ack, nil
-}
-
 type NewMessage struct {
 	Message     string `json:"message"`      // The message formatted with HTML.
 	RawMessage  string `json:"raw_message"`  // The message as it was typed.
@@ -137,12 +121,6 @@ type MessageAcknowledgement struct {
 	Success bool        `json:"success"` // Whether the message was sent successfully.
 }
 
-type PendingMessage struct {
-	Confirmation chan *MessageAcknowledgement // Channel to send confirmation messages to
-	Time         time.Time                    // Time the message was scheduled to be sent
-	Success      bool                         // Whether the message was sent successfully
-}
-
 type SocketPrivateMessage struct {
 	inner  NewMessage
 	Text   string
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChatWorkersOrder(t *testing.T) {
+	workers := newChatWorkers()
+
+	var mu sync.Mutex
+	var got []int
+	var wg sync.WaitGroup
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		workers.run("chat", func() {
+			defer wg.Done()
+
+			mu.Lock()
+			got = append(got, i)
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+
+	for i, value := range got {
+		if value != i {
+			t.Fatalf("work %d ran at position %d, want each chat's work in order: %v", value, i, got)
+		}
+	}
+}
+
+func TestChatWorkersConcurrent(t *testing.T) {
+	workers := newChatWorkers()
+	blocked := make(chan struct{})
+	done := make(chan struct{})
+
+	// A chat which never finishes its work must not hold up another chat
+	workers.run("slow", func() {
+		<-blocked
+	})
+
+	workers.run("fast", func() {
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("work of one chat waited for another chat")
+	}
+
+	close(blocked)
+}
+
+func TestChatWorkersPanic(t *testing.T) {
+	workers := newChatWorkers()
+	done := make(chan struct{})
+
+	workers.run("chat", func() {
+		panic("handler failed")
+	})
+
+	workers.run("chat", func() {
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a panic stopped the rest of the chat's work")
+	}
+}
@@ -108,13 +108,13 @@ func Sync(deep bool) error {
 			fmt.Println(highlight, " ->", apiItemInSet.ID, "|", apiItemInSet.En.ItemName, "\x1b[0m")
 
 		}
-
-		// Wait just long enough to not upset Cloudflare's anti-DDoS system
-		time.Sleep(time.Millisecond * 330)
 	}
 
 	fmt.Println("Sync complete")
 
+	// Pick up any new or renamed items on the next search
+	Items.Invalidate()
+
 	err = DB.SetLastSynced(time.Now(), deep)
 
 	if err != nil {
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"fmt"
+)
+
+// Whisper builds the in-game message answering an order, in the format warframe.market itself uses.
+// A sell order is answered with an offer to buy, and a buy order with an offer to sell.
+func Whisper(name string, orderType string, item string, rank *int, platinum int) string {
+	verb := "buy"
+
+	if OrderType(orderType) == OrderTypeBuy {
+		verb = "sell"
+	}
+
+	if rank != nil {
+		item = fmt.Sprintf("%s (rank %d)", item, *rank)
+	}
+
+	return fmt.Sprintf("/w %s Hi! I want to %s: \"%s\" for %d platinum. (warframe.market)", name, verb, item, platinum)
+}
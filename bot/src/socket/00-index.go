@@ -1,6 +1,8 @@
 package socket
 
 import (
+	"errors"
+	"log"
 	"strings"
 	"vaportrader/src/services"
 )
@@ -12,6 +14,7 @@ type SocketCommand struct {
 	Category    string
 	Cooldown    int
 	Aliases     []string
+	Arguments   []ArgumentSpec
 	Handler     SocketCommandHandlerMethod
 	Permissions SocketCommandPermissionsMethod
 }
@@ -20,21 +23,51 @@ type CommandContext struct {
 	Command   string
 	message   *services.NewMessage
 	Arguments []string
+	Args      *Arguments
 	Author    string
 	User      *services.User
 }
 
-func BuildCommandContext(s *services.SocketClient, msg *services.NewMessage) *CommandContext {
-	var user *services.User
-	user, _ = services.DB.GetUserByWFMID(msg.MessageFrom)
+// BuildCommandContext resolves the sender of the message, creating a shadow identity for them if they are new.
+// Messages only carry the ID of the sender, so their name and locale come from their recent orders or profile.
+func BuildCommandContext(s *services.SocketClient, msg *services.NewMessage, tokens []string) (*CommandContext, error) {
+	user, err := services.DB.ResolveSocketUser(services.Presences.Identity(msg.MessageFrom))
+
+	if err != nil {
+		return nil, err
+	}
 
 	return &CommandContext{
-		Command:   strings.Split(msg.RawMessage, " ")[0],
+		Command:   tokens[0],
 		message:   msg,
-		Arguments: strings.Split(msg.RawMessage, " ")[1:],
+		Arguments: tokens[1:],
+		Args:      &Arguments{values: map[string]string{}},
 		Author:    msg.MessageFrom,
 		User:      user,
+	}, nil
+}
+
+// Locale returns the saved language of the author, falling back to the locale of their warframe.market profile,
+// or nil if neither is known
+func (c *CommandContext) Locale() *string {
+	if c.User == nil {
+		return nil
 	}
+
+	if c.User.Locale.Valid {
+		return &c.User.Locale.String
+	}
+
+	if c.User.WfmLocale.Valid {
+		return &c.User.WfmLocale.String
+	}
+
+	return nil
+}
+
+// Translate returns the value of the key in the language of the author
+func (c *CommandContext) Translate(key string, params *map[string]interface{}) string {
+	return services.LanguageManager.Get(c.Locale(), key, params)
 }
 
 func (c *CommandContext) GetArgument(index int) string {
@@ -44,7 +77,8 @@ func (c *CommandContext) GetArgument(index int) string {
 	return ""
 }
 
-func (c *CommandContext) Reply(text string) (*services.MessageAcknowledgement, error) {
+// Reply queues the message in the outbox, without waiting for it to be delivered
+func (c *CommandContext) Reply(text string) error {
 	return c.message.Reply(text)
 }
 
@@ -67,51 +101,116 @@ func (c *SocketCommandHandler) Register(cmd SocketCommand) {
 	c.Index[cmd.Name] = cmd
 }
 
+// Find returns the command with the given name or alias, ignoring case
+func (c *SocketCommandHandler) Find(name string) (SocketCommand, bool) {
+	for _, cmd := range c.Index {
+		if strings.EqualFold(cmd.Name, name) {
+			return cmd, true
+		}
+	}
+
+	for _, cmd := range c.Index {
+		for _, alias := range cmd.Aliases {
+			if strings.EqualFold(alias, name) {
+				return cmd, true
+			}
+		}
+	}
+
+	return SocketCommand{}, false
+}
+
 func (c *SocketCommandHandler) HandleCommand(s *services.SocketClient, msg *services.NewMessage) {
-	words := strings.Split(msg.RawMessage, " ")
 	msg.Acknowledge()
 
-	if len(words) < 1 {
-		return
+	tokens, tokenErr := Tokenize(msg.RawMessage)
+
+	if tokenErr != nil {
+		// Still find the command, so the author is told what went wrong
+		tokens = strings.Fields(msg.RawMessage)
 	}
 
-	cmdName := words[0]
+	if len(tokens) < 1 {
+		return
+	}
 
-	cmd, ok := c.Index[cmdName]
+	cmd, ok := c.Find(tokens[0])
 
 	if !ok {
 		return
 	}
 
-	ctx := BuildCommandContext(s, msg)
+	ctx, err := BuildCommandContext(s, msg, tokens)
+
+	if err != nil {
+		log.Printf("Error resolving the sender of a private message: %s", err)
+		_ = msg.Reply(services.LanguageManager.Get(nil, "commands.handler.errors.generic.failed", &map[string]interface{}{
+			"Error": err.Error(),
+		}))
+		return
+	}
+
+	if tokenErr != nil {
+		c.replyUsage(ctx, cmd, tokenErr)
+		return
+	}
 
 	permitted, reason, err := cmd.Permissions(s, ctx)
 
 	if err != nil {
-		_, _ = msg.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.handler.errors.perms.failed", &map[string]interface{}{
+		_ = msg.Reply(ctx.Translate("commands.handler.errors.perms.failed", &map[string]interface{}{
 			"Error": err.Error(),
 		}))
 		return
 	}
 
 	if !permitted {
-		_, _ = msg.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.handler.errors.perms.unauthorized", &map[string]interface{}{
+		_ = msg.Reply(ctx.Translate("commands.handler.errors.perms.unauthorized", &map[string]interface{}{
 			"Reason": reason,
 		}))
 		return
 	}
 
+	args, usageErr := ParseArguments(cmd.Arguments, ctx.Arguments)
+
+	if usageErr != nil {
+		c.replyUsage(ctx, cmd, usageErr)
+		return
+	}
+
+	ctx.Args = args
+
 	err = cmd.Handler(s, ctx)
 
+	// Handlers may reject their arguments after parsing them, e.g. for subcommands
+	if errors.As(err, &usageErr) {
+		c.replyUsage(ctx, cmd, usageErr)
+		return
+	}
+
 	if err != nil {
-		_, _ = msg.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.handler.errors.generic.failed", &map[string]interface{}{
+		_ = msg.Reply(ctx.Translate("commands.handler.errors.generic.failed", &map[string]interface{}{
 			"Error": err.Error(),
 		}))
 		return
 	}
 }
 
+// replyUsage tells the author why their arguments were rejected, along with the usage of the command
+func (c *SocketCommandHandler) replyUsage(ctx *CommandContext, cmd SocketCommand, usageErr *UsageError) {
+	if usageErr.Params == nil {
+		usageErr.Params = map[string]interface{}{}
+	}
+
+	_ = ctx.Reply(ctx.Translate("commands.handler.errors.usage.reply", &map[string]interface{}{
+		"Error": ctx.Translate(usageErr.Key, &usageErr.Params),
+		"Usage": Usage(cmd.Name, cmd.Arguments),
+	}))
+}
+
 func Load() {
 	CMDHandler.Register(HelpCommand())
 	CMDHandler.Register(LinkCommand())
+	CMDHandler.Register(PriceCommand())
+	CMDHandler.Register(AlertCommand())
 }
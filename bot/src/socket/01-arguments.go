@@ -0,0 +1,281 @@
+package socket
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type ArgumentType int
+
+const (
+	ArgumentString ArgumentType = iota // A single word, or a quoted phrase
+	ArgumentNumber                     // A whole number
+	ArgumentBool                       // A flag which is either present or not, only valid for flags
+	ArgumentRest                       // Every remaining positional word, joined by spaces (e.g. an item name)
+)
+
+// ArgumentSpec declares a single argument of a socket command
+type ArgumentSpec struct {
+	Name     string       // The name of the argument, also used as the long flag name (--name)
+	Short    string       // The short flag name (-n), only used for flags
+	Type     ArgumentType // How the value is read
+	Flag     bool         // Whether the argument is given as a flag, rather than by position
+	Required bool         // Whether the argument must be given
+	Default  string       // The value used when the argument is not given
+}
+
+// UsageError describes why the arguments of a command could not be parsed.
+// The key and params are rendered through the language manager.
+type UsageError struct {
+	Key    string
+	Params map[string]interface{}
+}
+
+func (e *UsageError) Error() string {
+	return e.Key
+}
+
+func usageError(key string, params map[string]interface{}) *UsageError {
+	return &UsageError{Key: "commands.handler.errors.usage." + key, Params: params}
+}
+
+// Tokenize splits the input into words on any whitespace.
+// Double or single quotes group words together, and a backslash escapes the next character inside quotes.
+// A quote only opens a group at the start of a word, so apostrophes like "Baro's" are kept as they are.
+func Tokenize(input string) ([]string, *UsageError) {
+	var tokens []string
+	var current strings.Builder
+
+	var quote rune
+	inToken := false
+	escaped := false
+
+	for _, r := range input {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case (r == '"' || r == '\'') && !inToken:
+			quote = r
+			inToken = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, usageError("unterminated_quote", nil)
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+// Arguments holds the parsed values of a command's arguments
+type Arguments struct {
+	values map[string]string
+}
+
+// Has returns true if the argument was given, or has a default value
+func (a *Arguments) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// String returns the value of the argument, or an empty string if it was not given
+func (a *Arguments) String(name string) string {
+	return a.values[name]
+}
+
+// Number returns the value of the argument, or zero if it was not given.
+// The value has already been validated by the parser.
+func (a *Arguments) Number(name string) int {
+	n, _ := strconv.Atoi(a.values[name])
+	return n
+}
+
+// Bool returns true if the flag was given
+func (a *Arguments) Bool(name string) bool {
+	return a.values[name] == "true"
+}
+
+// ParseArguments matches the tokens against the specs, returning a usage error for anything that doesn't fit
+func ParseArguments(specs []ArgumentSpec, tokens []string) (*Arguments, *UsageError) {
+	args := &Arguments{values: map[string]string{}}
+
+	var positional []string
+
+	for index := 0; index < len(tokens); index++ {
+		token := tokens[index]
+
+		// A lone dash, or a negative number, is a value rather than a flag
+		if len(token) < 2 || token[0] != '-' || isNumber(token) {
+			positional = append(positional, token)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(token, "-"), "=")
+		spec, ok := findFlag(specs, name, !strings.HasPrefix(token, "--"))
+
+		if !ok {
+			return nil, usageError("unknown_flag", map[string]interface{}{"Flag": token})
+		}
+
+		if spec.Type == ArgumentBool {
+			if hasValue {
+				parsed, err := strconv.ParseBool(value)
+
+				if err != nil {
+					return nil, usageError("invalid_bool", map[string]interface{}{"Argument": spec.Name, "Value": value})
+				}
+
+				value = strconv.FormatBool(parsed)
+			} else {
+				value = "true"
+			}
+		} else if !hasValue {
+			if index+1 >= len(tokens) {
+				return nil, usageError("missing_value", map[string]interface{}{"Flag": token})
+			}
+
+			index++
+			value = tokens[index]
+		}
+
+		if err := setArgument(args, spec, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, spec := range specs {
+		if spec.Flag {
+			continue
+		}
+
+		if len(positional) == 0 {
+			break
+		}
+
+		value := positional[0]
+		positional = positional[1:]
+
+		if spec.Type == ArgumentRest {
+			value = strings.Join(append([]string{value}, positional...), " ")
+			positional = nil
+		}
+
+		if err := setArgument(args, spec, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(positional) > 0 {
+		return nil, usageError("too_many", map[string]interface{}{"Value": strings.Join(positional, " ")})
+	}
+
+	for _, spec := range specs {
+		if args.Has(spec.Name) {
+			continue
+		}
+
+		if spec.Required {
+			return nil, usageError("missing", map[string]interface{}{"Argument": spec.Name})
+		}
+
+		if spec.Default != "" {
+			args.values[spec.Name] = spec.Default
+		}
+	}
+
+	return args, nil
+}
+
+// Usage describes the arguments in the conventional form, e.g. "price <item...> [--rank <rank:number>]"
+func Usage(name string, specs []ArgumentSpec) string {
+	parts := []string{name}
+
+	for _, spec := range specs {
+		var part string
+
+		switch spec.Type {
+		case ArgumentRest:
+			part = "<" + spec.Name + "...>"
+		case ArgumentNumber:
+			part = "<" + spec.Name + ":number>"
+		default:
+			part = "<" + spec.Name + ">"
+		}
+
+		if spec.Flag {
+			flag := "--" + spec.Name
+
+			if spec.Short != "" {
+				flag = "-" + spec.Short + "|" + flag
+			}
+
+			if spec.Type == ArgumentBool {
+				part = flag
+			} else {
+				part = flag + " " + part
+			}
+		}
+
+		if !spec.Required {
+			part = "[" + part + "]"
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// setArgument validates the value against the type of the argument, and stores it
+func setArgument(args *Arguments, spec ArgumentSpec, value string) *UsageError {
+	if spec.Type == ArgumentNumber {
+		if _, err := strconv.Atoi(value); err != nil {
+			return usageError("invalid_number", map[string]interface{}{"Argument": spec.Name, "Value": value})
+		}
+	}
+
+	args.values[spec.Name] = value
+
+	return nil
+}
+
+// findFlag returns the flag spec with the given long name, or short name if short is set
+func findFlag(specs []ArgumentSpec, name string, short bool) (ArgumentSpec, bool) {
+	for _, spec := range specs {
+		if !spec.Flag {
+			continue
+		}
+
+		if (short && spec.Short != "" && spec.Short == name) || (!short && strings.EqualFold(spec.Name, name)) {
+			return spec, true
+		}
+	}
+
+	return ArgumentSpec{}, false
+}
+
+func isNumber(token string) bool {
+	_, err := strconv.ParseFloat(token, 64)
+	return err == nil
+}
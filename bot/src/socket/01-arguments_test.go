@@ -0,0 +1,204 @@
+package socket
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input  string
+		tokens []string
+		err    string
+	}{
+		{input: "", tokens: nil},
+		{input: "  price  ash prime  ", tokens: []string{"price", "ash", "prime"}},
+		{input: `price "ash prime set" --rank 3`, tokens: []string{"price", "ash prime set", "--rank", "3"}},
+		{input: `alert add 'nova prime' 50`, tokens: []string{"alert", "add", "nova prime", "50"}},
+		{input: `"say \"hi\""`, tokens: []string{`say "hi"`}},
+		{input: `""`, tokens: []string{""}},
+		{input: "Baro's stock", tokens: []string{"Baro's", "stock"}},
+		{input: "don't do it", tokens: []string{"don't", "do", "it"}},
+		{input: `size=5"`, tokens: []string{`size=5"`}},
+		{input: `"ash prime`, err: "commands.handler.errors.usage.unterminated_quote"},
+		{input: `'ash \`, err: "commands.handler.errors.usage.unterminated_quote"},
+	}
+
+	for _, test := range tests {
+		tokens, err := Tokenize(test.input)
+
+		if test.err != "" {
+			if err == nil || err.Key != test.err {
+				t.Errorf("Tokenize(%q) error = %v, want %s", test.input, err, test.err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Tokenize(%q) returned error %s", test.input, err.Key)
+			continue
+		}
+
+		if !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("Tokenize(%q) = %q, want %q", test.input, tokens, test.tokens)
+		}
+	}
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range []string{"", "price ash prime", `price "ash prime" --rank=3`, "Baro's", `'don't'`, `"\`, "a\tb\nc"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := Tokenize(input)
+
+		if err != nil {
+			if tokens != nil {
+				t.Fatalf("Tokenize(%q) returned both tokens %q and error %s", input, tokens, err.Key)
+			}
+
+			if !strings.HasPrefix(err.Key, "commands.handler.errors.usage.") {
+				t.Fatalf("Tokenize(%q) returned an error without a usage key: %s", input, err.Key)
+			}
+
+			// Only a quote can be left open, and single quotes only open at the start of a word
+			if !strings.Contains(input, `"`) && !opensQuote(input) {
+				t.Fatalf("Tokenize(%q) returned error %s without an opening quote", input, err.Key)
+			}
+
+			return
+		}
+
+		// Messages arrive as JSON strings, so the properties below only hold for valid UTF-8
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		// Without escapes, or quotes at the start of a word, the input is only split on whitespace.
+		// This covers apostrophes inside words, e.g. "Baro's".
+		if !strings.ContainsAny(input, `"\`) && !opensQuote(input) && !reflect.DeepEqual(tokens, nilIfEmpty(strings.Fields(input))) {
+			t.Fatalf("Tokenize(%q) = %q, want %q", input, tokens, strings.Fields(input))
+		}
+	})
+}
+
+// argumentSpecs covers every argument type, both by position and as flags
+var argumentSpecs = []ArgumentSpec{
+	{Name: "action", Type: ArgumentString, Required: true},
+	{Name: "quantity", Type: ArgumentNumber},
+	{Name: "item", Type: ArgumentRest},
+	{Name: "rank", Short: "r", Type: ArgumentNumber, Flag: true},
+	{Name: "buy", Short: "b", Type: ArgumentBool, Flag: true},
+	{Name: "platform", Short: "p", Type: ArgumentString, Flag: true, Default: "pc"},
+}
+
+func TestParseArguments(t *testing.T) {
+	tests := []struct {
+		input  string
+		specs  []ArgumentSpec
+		values map[string]string
+		err    string
+	}{
+		{input: "add", values: map[string]string{"action": "add", "platform": "pc"}},
+		{input: "add 5 ash prime set", values: map[string]string{"action": "add", "quantity": "5", "item": "ash prime set", "platform": "pc"}},
+		{input: `"add" 5 "ash prime"`, values: map[string]string{"action": "add", "quantity": "5", "item": "ash prime", "platform": "pc"}},
+		{input: "add -5", values: map[string]string{"action": "add", "quantity": "-5", "platform": "pc"}},
+		{input: "add 5 - ash", values: map[string]string{"action": "add", "quantity": "5", "item": "- ash", "platform": "pc"}},
+		{input: "add 5 serration --rank 10", values: map[string]string{"action": "add", "quantity": "5", "item": "serration", "rank": "10", "platform": "pc"}},
+		{input: "add --rank=10 5 serration", values: map[string]string{"action": "add", "quantity": "5", "item": "serration", "rank": "10", "platform": "pc"}},
+		{input: "add 5 serration -r 10", values: map[string]string{"action": "add", "quantity": "5", "item": "serration", "rank": "10", "platform": "pc"}},
+		{input: "add --RANK 10", values: map[string]string{"action": "add", "rank": "10", "platform": "pc"}},
+		{input: "add --buy", values: map[string]string{"action": "add", "buy": "true", "platform": "pc"}},
+		{input: "add -b=0", values: map[string]string{"action": "add", "buy": "false", "platform": "pc"}},
+		{input: "add -p xbox", values: map[string]string{"action": "add", "platform": "xbox"}},
+		{input: "add --platform=switch", values: map[string]string{"action": "add", "platform": "switch"}},
+		{input: "", err: "commands.handler.errors.usage.missing"},
+		{input: "--buy", err: "commands.handler.errors.usage.missing"},
+		{input: "add five", err: "commands.handler.errors.usage.invalid_number"},
+		{input: "add 5 serration --rank ten", err: "commands.handler.errors.usage.invalid_number"},
+		{input: "add --rank=", err: "commands.handler.errors.usage.invalid_number"},
+		{input: "add --buy=maybe", err: "commands.handler.errors.usage.invalid_bool"},
+		{input: "add --unknown", err: "commands.handler.errors.usage.unknown_flag"},
+		{input: "add -rank 10", err: "commands.handler.errors.usage.unknown_flag"},
+		{input: "add --r 10", err: "commands.handler.errors.usage.unknown_flag"},
+		{input: "add --rank", err: "commands.handler.errors.usage.missing_value"},
+		{input: "add -p", err: "commands.handler.errors.usage.missing_value"},
+		{input: "add 5 ash prime", specs: argumentSpecs[:2], err: "commands.handler.errors.usage.too_many"},
+	}
+
+	for _, test := range tests {
+		specs := test.specs
+
+		if specs == nil {
+			specs = argumentSpecs
+		}
+
+		tokens, err := Tokenize(test.input)
+
+		if err != nil {
+			t.Errorf("Tokenize(%q) returned error %s", test.input, err.Key)
+			continue
+		}
+
+		args, err := ParseArguments(specs, tokens)
+
+		if test.err != "" {
+			if err == nil || err.Key != test.err {
+				t.Errorf("ParseArguments(%q) error = %v, want %s", test.input, err, test.err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseArguments(%q) returned error %s", test.input, err.Key)
+			continue
+		}
+
+		if !reflect.DeepEqual(args.values, test.values) {
+			t.Errorf("ParseArguments(%q) = %v, want %v", test.input, args.values, test.values)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []ArgumentSpec
+		usage string
+	}{
+		{name: "ping", specs: nil, usage: "ping"},
+		{name: "alert", specs: argumentSpecs, usage: "alert <action> [<quantity:number>] [<item...>] [-r|--rank <rank:number>] [-b|--buy] [-p|--platform <platform>]"},
+		{name: "link", specs: []ArgumentSpec{{Name: "code", Type: ArgumentString, Flag: true, Required: true}}, usage: "link --code <code>"},
+		{name: "price", specs: []ArgumentSpec{{Name: "item", Type: ArgumentRest, Required: true}, {Name: "rank", Type: ArgumentNumber, Flag: true}}, usage: "price <item...> [--rank <rank:number>]"},
+	}
+
+	for _, test := range tests {
+		if usage := Usage(test.name, test.specs); usage != test.usage {
+			t.Errorf("Usage(%q) = %q, want %q", test.name, usage, test.usage)
+		}
+	}
+}
+
+// opensQuote returns true if a single quote starts any word of the input
+func opensQuote(input string) bool {
+	for _, word := range strings.Fields(input) {
+		if strings.HasPrefix(word, "'") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nilIfEmpty(tokens []string) []string {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	return tokens
+}
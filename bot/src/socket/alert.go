@@ -0,0 +1,272 @@
+package socket
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"vaportrader/src/services"
+
+	"gorm.io/gorm"
+)
+
+// The price modes of an alert, and the orders they watch
+const (
+	alertModeBelow = "below" // Sell orders at or below the price
+	alertModeAbove = "above" // Buy orders at or above the price
+	// Sellers going in game, optionally at or below the price
+	alertModeInGame = services.AlertModeInGame
+)
+
+func AlertCommand() SocketCommand {
+	return SocketCommand{
+		Name:        services.LanguageManager.Get(nil, "commands.wfm.alert.name", nil),
+		Description: services.LanguageManager.Get(nil, "commands.wfm.alert.description", nil),
+		Usage:       services.LanguageManager.Get(nil, "commands.wfm.alert.usage", nil),
+		Category:    "Market",
+		Cooldown:    5,
+		Aliases:     services.LanguageManager.GetList(nil, "commands.wfm.alert.aliases", nil),
+		Arguments: []ArgumentSpec{
+			{Name: "action", Type: ArgumentString, Required: true},
+			{Name: "arguments", Type: ArgumentRest},
+			{Name: "rank", Short: "r", Type: ArgumentNumber, Flag: true},
+		},
+		Handler:     AlertCommandHandler,
+		Permissions: AlertCommandPermissions,
+	}
+}
+
+func AlertCommandHandler(s *services.SocketClient, ctx *CommandContext) error {
+	if ctx.User.IsShadow() {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.alert.dialog.not_linked", &map[string]interface{}{
+			"CommandName": ctx.Translate("commands.discord.link.name", nil),
+		}))
+		return nil
+	}
+
+	words := strings.Fields(ctx.Args.String("arguments"))
+
+	switch strings.ToLower(ctx.Args.String("action")) {
+	case "add":
+		return AlertAddHandler(ctx, words)
+	case "list":
+		return AlertListHandler(ctx)
+	case "remove", "delete":
+		return AlertRemoveHandler(ctx, words)
+	}
+
+	return usageError("unknown_action", map[string]interface{}{"Action": ctx.Args.String("action"), "Actions": "add, list, remove"})
+}
+
+// AlertAddHandler handles "alert add <item> <below|above> <price>" and "alert add <item> ingame [price]"
+func AlertAddHandler(ctx *CommandContext, words []string) error {
+	if len(words) < 2 {
+		return &UsageError{Key: "commands.wfm.alert.usage.add"}
+	}
+
+	price := 0
+	mode := strings.ToLower(words[len(words)-1])
+	query := strings.Join(words[:len(words)-1], " ")
+
+	// In-game alerts don't need a price, every other mode ends with one
+	if mode != alertModeInGame {
+		if len(words) < 3 {
+			return &UsageError{Key: "commands.wfm.alert.usage.add"}
+		}
+
+		var err error
+		price, err = strconv.Atoi(words[len(words)-1])
+
+		if err != nil || price < 1 {
+			return usageError("invalid_number", map[string]interface{}{"Argument": "price", "Value": words[len(words)-1]})
+		}
+
+		mode = strings.ToLower(words[len(words)-2])
+		query = strings.Join(words[:len(words)-2], " ")
+	}
+
+	if mode != alertModeBelow && mode != alertModeAbove && mode != alertModeInGame {
+		return &UsageError{Key: "commands.wfm.alert.usage.add"}
+	}
+
+	match, err := services.Items.Find(query)
+
+	if errors.Is(err, services.ErrItemNotFound) {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.price.dialog.not_found", &map[string]interface{}{
+			"Query": query,
+		}))
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	active, err := services.CountActivePriceAlertsForUser(ctx.User.ID)
+
+	if err != nil {
+		return err
+	}
+
+	limit := ctx.User.Limits().PriceAlerts
+
+	if active >= int64(limit) {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.alert.dialog.quota", &map[string]interface{}{
+			"Limit": limit,
+		}))
+		return nil
+	}
+
+	platform := "pc"
+
+	if ctx.User.PreferredPlatform.Valid && ctx.User.PreferredPlatform.String != "" {
+		platform = ctx.User.PreferredPlatform.String
+	}
+
+	alert := &services.Alert{
+		UserId:    ctx.User.ID,
+		ItemId:    match.ItemID,
+		PriceMode: mode,
+		Platform:  platform,
+		Active:    true,
+	}
+
+	if ctx.Args.Has("rank") {
+		alert.ModRank = sql.NullInt32{Int32: int32(ctx.Args.Number("rank")), Valid: true}
+	}
+
+	if mode == alertModeBelow || mode == alertModeInGame {
+		alert.OrderType = string(services.OrderTypeSell)
+		alert.UpperPrice = uint32(price)
+	} else {
+		alert.OrderType = string(services.OrderTypeBuy)
+		alert.LowerPrice = uint32(price)
+	}
+
+	err = services.AddPriceAlert(alert)
+
+	if err != nil {
+		return err
+	}
+
+	_ = ctx.Reply(ctx.Translate("commands.wfm.alert.dialog.added", &map[string]interface{}{
+		"ID":    alert.ID,
+		"Alert": describeAlert(ctx, alert),
+	}))
+
+	return nil
+}
+
+// AlertListHandler handles "alert list"
+func AlertListHandler(ctx *CommandContext) error {
+	alerts, err := services.GetActivePriceAlertsForUser(ctx.User.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if len(alerts) == 0 {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.alert.dialog.empty", nil))
+		return nil
+	}
+
+	var response strings.Builder
+
+	response.WriteString(ctx.Translate("commands.wfm.alert.dialog.list", &map[string]interface{}{
+		"Count": len(alerts),
+		"Limit": ctx.User.Limits().PriceAlerts,
+	}))
+
+	for _, alert := range alerts {
+		key := "commands.wfm.alert.dialog.entry"
+
+		if !alert.Active {
+			key = "commands.wfm.alert.dialog.entry_inactive"
+		}
+
+		response.WriteString("\n")
+		response.WriteString(ctx.Translate(key, &map[string]interface{}{
+			"ID":    alert.ID,
+			"Alert": describeAlert(ctx, alert),
+			"Hits":  alert.Hits,
+		}))
+	}
+
+	_ = ctx.Reply(response.String())
+
+	return nil
+}
+
+// AlertRemoveHandler handles "alert remove <id>"
+func AlertRemoveHandler(ctx *CommandContext, words []string) error {
+	if len(words) != 1 {
+		return &UsageError{Key: "commands.wfm.alert.usage.remove"}
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(words[0], "#"), 10, 32)
+
+	if err != nil {
+		return usageError("invalid_number", map[string]interface{}{"Argument": "id", "Value": words[0]})
+	}
+
+	alert, err := services.GetPriceAlertForUser(ctx.User.ID, uint32(id))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.alert.dialog.not_found", &map[string]interface{}{
+			"ID": id,
+		}))
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = services.DeletePriceAlert(alert)
+
+	if err != nil {
+		return err
+	}
+
+	_ = ctx.Reply(ctx.Translate("commands.wfm.alert.dialog.removed", &map[string]interface{}{
+		"ID":    alert.ID,
+		"Alert": describeAlert(ctx, alert),
+	}))
+
+	return nil
+}
+
+// describeAlert writes the alert in the language of the author, e.g. "Ember Prime Set below 120 platinum (pc)"
+func describeAlert(ctx *CommandContext, alert *services.Alert) string {
+	price := alert.LowerPrice
+
+	if alert.PriceMode == alertModeBelow || alert.PriceMode == alertModeInGame {
+		price = alert.UpperPrice
+	}
+
+	key := "commands.wfm.alert.dialog.description"
+
+	if alert.PriceMode == alertModeInGame && price == 0 {
+		key = "commands.wfm.alert.dialog.description_any"
+	}
+
+	rank := ""
+
+	if alert.ModRank.Valid {
+		rank = ctx.Translate("commands.wfm.price.dialog.rank", &map[string]interface{}{
+			"Rank": alert.ModRank.Int32,
+		})
+	}
+
+	return ctx.Translate(key, &map[string]interface{}{
+		"Item":     services.Items.Name(alert.ItemId, ctx.Locale()),
+		"Rank":     rank,
+		"Mode":     alert.PriceMode,
+		"Price":    services.LanguageManager.FormatPlatinum(ctx.Locale(), int64(price)),
+		"Platform": alert.Platform,
+	})
+}
+
+func AlertCommandPermissions(s *services.SocketClient, ctx *CommandContext) (bool, string, error) {
+	return true, "", nil
+}
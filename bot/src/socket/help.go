@@ -1,7 +1,7 @@
 package socket
 
 import (
-	"fmt"
+	"strings"
 	"vaportrader/src/services"
 )
 
@@ -12,43 +12,61 @@ func HelpCommand() SocketCommand {
 		Usage:       services.LanguageManager.Get(nil, "commands.wfm.help.usage", nil),
 		Category:    "General",
 		Cooldown:    5,
-		Aliases:     []string{"commands"},
+		Aliases:     services.LanguageManager.GetList(nil, "commands.wfm.help.aliases", nil),
+		Arguments: []ArgumentSpec{
+			{Name: "action", Type: ArgumentString},
+		},
 		Handler:     HelpCommandHandler,
 		Permissions: HelpCommandPermissions,
 	}
 }
 
 func HelpCommandHandler(s *services.SocketClient, ctx *CommandContext) error {
-	if len(ctx.Arguments) < 1 {
+	if !ctx.Args.Has("action") {
+		var response strings.Builder
 
-		var response string = "Hi there! This account is operated by an automated system.\nThese are all of the things I can do here:\n"
+		response.WriteString(ctx.Translate("commands.wfm.help.dialog.intro", nil))
+		response.WriteString("\n")
 
 		for _, cmd := range CMDHandler.Index {
-			response += fmt.Sprintf("\n- `%s` - %s", cmd.Name, cmd.Description)
+			response.WriteString("\n")
+			response.WriteString(ctx.Translate("commands.wfm.help.dialog.entry", &map[string]interface{}{
+				"CommandName": cmd.Name,
+				"Description": cmd.Description,
+			}))
 		}
 
-		response += "\n\nThis is all for now."
+		response.WriteString("\n\n")
+		response.WriteString(ctx.Translate("commands.wfm.help.dialog.outro", nil))
 
-		ctx.Reply(response)
+		_ = ctx.Reply(response.String())
 		return nil
 	}
 
-	commandName := ctx.GetArgument(0)
+	commandName := ctx.Args.String("action")
 
-	if _, ok := CMDHandler.Index[commandName]; !ok {
-		ctx.Reply(fmt.Sprintf("Action '%s' not found.", commandName))
+	cmd, ok := CMDHandler.Find(commandName)
+
+	if !ok {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.help.dialog.not_found", &map[string]interface{}{
+			"CommandName": commandName,
+		}))
 		return nil
 	}
 
-	cmd := CMDHandler.Index[commandName]
-
-	response := fmt.Sprintf("**%s**\n\n%s\n\n", cmd.Name, cmd.Description)
+	response := ctx.Translate("commands.wfm.help.dialog.details", &map[string]interface{}{
+		"CommandName": cmd.Name,
+		"Description": cmd.Description,
+		"Usage":       Usage(cmd.Name, cmd.Arguments),
+	})
 
 	for _, alias := range cmd.Aliases {
-		response += fmt.Sprintf("Alias: `%s`\n", alias)
+		response += "\n" + ctx.Translate("commands.wfm.help.dialog.alias", &map[string]interface{}{
+			"Alias": alias,
+		})
 	}
 
-	ctx.Reply(response)
+	_ = ctx.Reply(response)
 	return nil
 }
 
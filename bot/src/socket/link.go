@@ -1,9 +1,9 @@
 package socket
 
 import (
-	"database/sql"
-	"time"
-	"vaportrader/src/commands"
+	"errors"
+	"log"
+	"vaportrader/src/linking"
 	"vaportrader/src/services"
 )
 
@@ -14,97 +14,97 @@ func LinkCommand() SocketCommand {
 		Usage:       services.LanguageManager.Get(nil, "commands.wfm.link.usage", nil),
 		Category:    "General",
 		Cooldown:    5,
-		Aliases:     []string{},
+		Aliases:     services.LanguageManager.GetList(nil, "commands.wfm.link.aliases", nil),
+		Arguments: []ArgumentSpec{
+			{Name: "code", Type: ArgumentString, Required: true},
+		},
 		Handler:     LinkCommandHandler,
 		Permissions: LinkCommandPermissions,
 	}
 }
 
 func LinkCommandHandler(s *services.SocketClient, ctx *CommandContext) error {
-	if len(ctx.Arguments) < 1 {
-		_, _ = ctx.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.dialog.invalid_code", nil))
+	linkCode, err := services.VerifyLinkCode(ctx.Args.String("code"), ctx.Author)
+
+	var locked *services.LinkLockedError
+
+	switch {
+	case errors.As(err, &locked):
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.locked", &map[string]interface{}{
+			"Time": services.LanguageManager.FormatRelative(ctx.Locale(), locked.Until),
+		}))
+		return nil
+	case errors.Is(err, services.ErrLinkCodeInvalid), errors.Is(err, services.ErrLinkCodeUsed):
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.unknown_code", &map[string]interface{}{
+			"CommandName": ctx.Translate("commands.discord.link.name", nil),
+		}))
+		return nil
+	case errors.Is(err, services.ErrLinkCodeExpired):
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.expired_code", &map[string]interface{}{
+			"CommandName": ctx.Translate("commands.discord.link.name", nil),
+		}))
 		return nil
+	case err != nil:
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.error", nil))
+		return err
 	}
 
-	code := ctx.GetArgument(0)
+	session, err := linking.Sessions.Active(linkCode.UserID)
 
-	if code == "" {
-		_, _ = ctx.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.dialog.invalid_code", nil))
+	// The code is valid, but the Discord side of the link was abandoned
+	if errors.Is(err, linking.ErrNoSession) || (err == nil && linking.State(session.State) != linking.StateCodeIssued) {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.expired_code", &map[string]interface{}{
+			"CommandName": ctx.Translate("commands.discord.link.name", nil),
+		}))
 		return nil
 	}
 
-	codeEntry := services.KV.Get(code + ":totp")
-
-	rawEntry := services.KV.Get(codeEntry.Value.(string))
-
-	if rawEntry != nil {
-		if rawEntry.Expiry.Before(time.Now()) {
-			_, _ = ctx.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.dialog.expired_code", &map[string]interface{}{
-				"CommandName": services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.name", nil),
-			}))
-		} else {
-			entry := rawEntry.Value.(commands.AccountLinkStatus)
-
-			user, err := services.DB.GetUserByID(entry.ID)
-
-			if err != nil {
-				_, _ = ctx.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.dialog.error", nil))
-				return err
-			}
-
-			if entry.Profile.ID != ctx.Author {
-				_, _ = ctx.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.dialog.not_owner", &map[string]interface{}{
-					"AccountName": entry.Profile.IngameName,
-				}))
-				return nil
-			}
-
-			user.WfmID = sql.NullString{String: ctx.Author, Valid: true}
-			user.WfmUsername = sql.NullString{String: entry.Profile.IngameName, Valid: true}
-			user.Locale = sql.NullString{String: entry.Profile.Locale, Valid: true}
-			user.PreferredPlatform = sql.NullString{String: entry.Profile.Platform, Valid: true}
-			user.LastSeen = entry.Profile.LastSeen
-			user.Awards = append(user.Awards, services.Award{
-				BadgeId: 4,
-				UserId:  user.ID,
-			})
-
-			err = services.DB.Save(user)
-
-			if err != nil {
-				_, _ = ctx.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.dialog.error", nil))
-
-				//_, _ = s.Session.InteractionResponseEdit(entry.Interaction, &discordgo.WebhookEdit{
-				//	Embeds: &[]*discordgo.MessageEmbed{
-				//		{
-				//			Title:       "An error occured while saving your account information.",
-				//			Description: "Please try again later.",
-				//			Color:       constants.ThemeColor,
-				//			Fields: []*discordgo.MessageEmbedField{
-				//				{
-				//					Name:   "Error",
-				//					Value:  err.Error(),
-				//					Inline: true,
-				//				},
-				//			},
-				//		},
-				//	},
-				//})
-
-				return err
-			}
-
-			_, _ = ctx.Reply(services.LanguageManager.Get(&ctx.User.Locale.String, "commands.wfm.link.dialog.success", &map[string]interface{}{
-				"UserName": user.WfmUsername.String,
-			}))
-
-			services.KV.Delete(entry.Code + ":totp")
-			services.KV.Delete(codeEntry.Value.(string))
+	if err != nil {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.error", nil))
+		return err
+	}
+
+	user, err := linking.Sessions.Complete(session, linkCode, ctx.Author)
+
+	switch {
+	case errors.Is(err, linking.ErrWrongSender):
+		// Someone else's code is as good as a wrong guess
+		err = services.RecordLinkFailure(ctx.Author)
+
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.not_owner", &map[string]interface{}{
+			"AccountName": session.ProfileName.String,
+		}))
+
+		// Let the Discord user know their code was sent from the wrong account
+		if rejectErr := linking.Sessions.RejectSender(session); rejectErr != nil {
+			log.Printf("Error rejecting the sender of the link session of %s: %s", session.UserID, rejectErr)
 		}
-	} else {
-		_, _ = ctx.Reply("This link does not exist, please request a new one. You can do this using the `/link` interaction in Discord.")
+
+		return err
+	case errors.Is(err, services.ErrLinkCodeUsed):
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.unknown_code", &map[string]interface{}{
+			"CommandName": ctx.Translate("commands.discord.link.name", nil),
+		}))
+		return nil
+	case errors.Is(err, services.ErrAccountAlreadyLinked):
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.already_linked", &map[string]interface{}{
+			"AccountName": session.ProfileName.String,
+		}))
+		return nil
+	case errors.Is(err, services.ErrPlatformAlreadyLinked):
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.platform_linked", &map[string]interface{}{
+			"Platform": session.ProfilePlatform.String,
+		}))
+		return nil
+	case err != nil:
+		_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.error", nil))
+		return err
 	}
 
+	_ = ctx.Reply(ctx.Translate("commands.wfm.link.dialog.success", &map[string]interface{}{
+		"UserName": user.WfmUsername.String,
+	}))
+
 	return nil
 }
 
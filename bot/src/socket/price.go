@@ -0,0 +1,127 @@
+package socket
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"vaportrader/src/services"
+)
+
+func PriceCommand() SocketCommand {
+	return SocketCommand{
+		Name:        services.LanguageManager.Get(nil, "commands.wfm.price.name", nil),
+		Description: services.LanguageManager.Get(nil, "commands.wfm.price.description", nil),
+		Usage:       services.LanguageManager.Get(nil, "commands.wfm.price.usage", nil),
+		Category:    "Market",
+		Cooldown:    5,
+		Aliases:     services.LanguageManager.GetList(nil, "commands.wfm.price.aliases", nil),
+		Arguments: []ArgumentSpec{
+			{Name: "item", Type: ArgumentRest, Required: true},
+			{Name: "rank", Short: "r", Type: ArgumentNumber, Flag: true},
+			{Name: "platform", Short: "p", Type: ArgumentString, Flag: true, Default: "pc"},
+			{Name: "online", Short: "o", Type: ArgumentBool, Flag: true},
+		},
+		Handler:     PriceCommandHandler,
+		Permissions: PriceCommandPermissions,
+	}
+}
+
+func PriceCommandHandler(s *services.SocketClient, ctx *CommandContext) error {
+	query := ctx.Args.String("item")
+
+	var rank *int
+
+	if ctx.Args.Has("rank") {
+		r := ctx.Args.Number("rank")
+		rank = &r
+	} else if words := strings.Fields(query); len(words) > 1 {
+		// Allow the rank to be given as a trailing number, e.g. "price primed continuity 10"
+		if r, err := strconv.Atoi(words[len(words)-1]); err == nil {
+			rank = &r
+			query = strings.Join(words[:len(words)-1], " ")
+		}
+	}
+
+	match, err := services.Items.Find(query)
+
+	if errors.Is(err, services.ErrItemNotFound) {
+		_ = ctx.Reply(ctx.Translate("commands.wfm.price.dialog.not_found", &map[string]interface{}{
+			"Query": query,
+		}))
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	summary, err := services.GetMarketSummary(match.Slug, ctx.Args.String("platform"), rank)
+
+	if err != nil {
+		return err
+	}
+
+	locale := ctx.Locale()
+	none := ctx.Translate("commands.wfm.price.dialog.none", nil)
+
+	sell := none
+	buy := none
+	median := none
+
+	if summary.LowestSell != nil {
+		sell = services.LanguageManager.FormatPlatinum(locale, int64(*summary.LowestSell))
+	}
+
+	// Only sellers who can trade right now, the ones in game first
+	if ctx.Args.Bool("online") {
+		sell = none
+
+		err = services.Orders.Ensure(match.ItemID, match.Slug, ctx.Args.String("platform"))
+
+		if err != nil {
+			return err
+		}
+
+		if sellers := services.Orders.Sellers(match.ItemID, ctx.Args.String("platform"), rank, true); len(sellers) > 0 {
+			sell = ctx.Translate("commands.wfm.price.dialog.online_seller", &map[string]interface{}{
+				"Price":    services.LanguageManager.FormatPlatinum(locale, int64(sellers[0].Price)),
+				"UserName": sellers[0].UserName,
+				"Status":   sellers[0].UserStatus,
+			})
+		}
+	}
+
+	if summary.HighestBuy != nil {
+		buy = services.LanguageManager.FormatPlatinum(locale, int64(*summary.HighestBuy))
+	}
+
+	if summary.Median != nil {
+		median = services.LanguageManager.FormatPlatinum(locale, int64(*summary.Median+0.5))
+	}
+
+	params := map[string]interface{}{
+		"Item":     services.Items.Name(match.ItemID, locale),
+		"Sell":     sell,
+		"Buy":      buy,
+		"Median":   median,
+		"Volume":   summary.Volume,
+		"Rank":     "",
+		"Filtered": summary.Filtered,
+	}
+
+	if rank != nil {
+		params["Rank"] = ctx.Translate("commands.wfm.price.dialog.rank", &map[string]interface{}{
+			"Rank": *rank,
+		})
+	}
+
+	_ = ctx.Reply(ctx.Translate("commands.wfm.price.dialog.summary", &params))
+
+	services.Bus.Publish(services.DomainPriceChecked, ctx.User.ID)
+
+	return nil
+}
+
+func PriceCommandPermissions(s *services.SocketClient, ctx *CommandContext) (bool, string, error) {
+	return true, "", nil
+}